@@ -0,0 +1,181 @@
+package gojson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Bases configures the extraction of fields that several structs have in
+// common, such as id, created_at and updated_at across the resources of an
+// API, into base structs embedded in each of them.
+type Bases struct {
+	// Threshold is the smallest share of the fields of a struct, from 0
+	// to 1, that a base must hold for the struct to embed it. Zero
+	// disables bases.
+	Threshold float64
+	// MinFields is the fewest fields a base holds, 2 if zero.
+	MinFields int
+	// DryRun leaves the structs as they are, only listing the bases that
+	// would be extracted in Model.Suggestions.
+	DryRun bool
+}
+
+// extractBases finds the fields the named structs of m have in common, as
+// configured by b.opts.Bases, and moves them to base structs that each
+// struct sharing them embeds. Bases are chosen greedily, those saving the
+// most fields first.
+func (b *modelBuilder) extractBases(m *Model) {
+	opts := b.opts.Bases
+	if opts.MinFields <= 0 {
+		opts.MinFields = 2
+	}
+	taken := make(map[string]bool)
+	for _, d := range m.Types {
+		taken[d.Name] = true
+	}
+	var structs []*Struct
+	for _, st := range m.Structs {
+		taken[st.Name] = true
+		if st.Tuple {
+			// the fields of tuples are elements of an array
+			continue
+		}
+		if opts.DryRun {
+			// work on copies, which embedding the bases changes
+			st = &Struct{Name: st.Name, Fields: append([]*Field(nil), st.Fields...)}
+		}
+		structs = append(structs, st)
+	}
+
+	for {
+		common, members := bestBase(structs, opts)
+		if members == nil {
+			return
+		}
+
+		name := "Base"
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("Base%v", i)
+		}
+		taken[name] = true
+		base := &Struct{Name: name}
+		names := make([]string, len(members))
+		for i, st := range members {
+			names[i] = st.Name
+		}
+		if b.opts.Comments {
+			base.Doc = fmt.Sprintf("%s holds the fields common to %s.", name, strings.Join(names, ", "))
+		}
+		var fieldNames []string
+		for _, f := range members[0].Fields {
+			if !common[f.signature()] {
+				continue
+			}
+			field := *f
+			for _, st := range members[1:] {
+				for _, other := range st.Fields {
+					if other.signature() == f.signature() {
+						field.Optional = field.Optional || other.Optional
+					}
+				}
+			}
+			base.Fields = append(base.Fields, &field)
+			fieldNames = append(fieldNames, f.Name)
+		}
+		m.Suggestions = append(m.Suggestions, fmt.Sprintf("%s: %s in %s",
+			name, strings.Join(fieldNames, ", "), strings.Join(names, ", ")))
+
+		embedded := &Field{Name: name, Type: &Type{Struct: base}, Embedded: true}
+		for _, t := range b.opts.Tags {
+			if t == "yaml" {
+				// yaml.v3 only promotes the fields of inline structs
+				embedded.Tag = `yaml:",inline"`
+			}
+		}
+		for _, st := range members {
+			fields := []*Field{embedded}
+			for _, f := range st.Fields {
+				if !common[f.signature()] {
+					fields = append(fields, f)
+				}
+			}
+			st.Fields = fields
+		}
+		structs = append(structs, base)
+		if !opts.DryRun {
+			m.Structs = append(m.Structs, base)
+		}
+	}
+}
+
+// bestBase returns the fields, by signature, of the base that saves the
+// most fields among structs, and the structs that would embed it, or nil.
+// Candidates are the fields each pair of structs has in common.
+func bestBase(structs []*Struct, opts Bases) (map[string]bool, []*Struct) {
+	var best map[string]bool
+	var bestMembers []*Struct
+	bestScore, bestKey := 0, ""
+	tried := make(map[string]bool)
+	for i, a := range structs {
+		for _, c := range structs[i+1:] {
+			common := make(map[string]bool)
+			var sigs []string
+			for _, f := range c.Fields {
+				if a.field(f.signature()) {
+					common[f.signature()] = true
+					sigs = append(sigs, f.signature())
+				}
+			}
+			sort.Strings(sigs)
+			key := strings.Join(sigs, "\n")
+			if len(common) < opts.MinFields || tried[key] {
+				continue
+			}
+			tried[key] = true
+
+			var members []*Struct
+			for _, st := range structs {
+				if float64(len(common)) >= opts.Threshold*float64(len(st.Fields)) && st.hasAll(common) {
+					members = append(members, st)
+				}
+			}
+			if len(members) < 2 {
+				continue
+			}
+			// a base of n fields embedded in k structs saves (n-1)*k - n lines
+			score := (len(common)-1)*len(members) - len(common)
+			if score > bestScore || score == bestScore && best != nil && key < bestKey {
+				best, bestMembers, bestScore, bestKey = common, members, score, key
+			}
+		}
+	}
+	return best, bestMembers
+}
+
+// signature identifies fields that are the same in every struct they are
+// in: of the same name, type and tags.
+func (f *Field) signature() string {
+	return f.Name + " " + f.Type.String() + " `" + f.Tag + "`"
+}
+
+// field reports whether s has a field of signature sig.
+func (s *Struct) field(sig string) bool {
+	for _, f := range s.Fields {
+		if f.signature() == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAll reports whether s has a field of every signature of sigs.
+func (s *Struct) hasAll(sigs map[string]bool) bool {
+	n := 0
+	for _, f := range s.Fields {
+		if sigs[f.signature()] {
+			n++
+		}
+	}
+	return n == len(sigs)
+}
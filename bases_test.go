@@ -0,0 +1,44 @@
+package gojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestBases tests that fields shared by several structs are extracted into an embedded base struct
+func TestBases(t *testing.T) {
+	generate := func(bases Bases, tags ...string) *Model {
+		return sessionModel(t, "bases", Options{Package: "api", Tags: tags, Bases: bases})
+	}
+	source := func(m *Model) string {
+		src, err := m.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(src)
+	}
+
+	m := generate(Bases{Threshold: 0.5}, "json")
+	expectSource(t, m, "expected_bases.go.out")
+	suggestions := []string{"Base: CreatedAt, ID, UpdatedAt in Issue, Repository, User"}
+	if !reflect.DeepEqual(m.Suggestions, suggestions) {
+		t.Errorf("%q (expected) != %q (actual)", suggestions, m.Suggestions)
+	}
+
+	if src := source(generate(Bases{Threshold: 0.5}, "yaml")); !strings.Contains(src, "\tBase  `yaml:\",inline\"`\n") {
+		t.Errorf("expected an inline base for yaml, got '%s'", src)
+	}
+
+	dry := generate(Bases{Threshold: 0.5, DryRun: true}, "json")
+	if actual, expected := source(dry), source(generate(Bases{}, "json")); actual != expected {
+		t.Errorf("dry run: '%s' (expected) != '%s' (actual)", expected, actual)
+	}
+	if !reflect.DeepEqual(dry.Suggestions, suggestions) {
+		t.Errorf("dry run: %q (expected) != %q (actual)", suggestions, dry.Suggestions)
+	}
+
+	if m := generate(Bases{Threshold: 0.9}, "json"); len(m.Suggestions) != 0 {
+		t.Errorf("expected no bases above the threshold, got %q", m.Suggestions)
+	}
+}
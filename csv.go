@@ -0,0 +1,137 @@
+package gojson
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamCsv reads comma separated values. The header row names the keys,
+// and every following row is a sample. Cells are typed by column: a column
+// is int64, float64, bool or time.Time if all of its non-empty cells parse
+// as one, and string otherwise. Empty cells are null. Rows are read one at
+// a time, and their cells sampled as text until the types of the columns
+// are resolved with the Model, so memory use does not grow with the rows.
+func StreamCsv(input io.Reader, s *Sampler) error {
+	return streamCsv(input, ',', s)
+}
+
+// StreamTsv reads tab separated values, as StreamCsv does.
+func StreamTsv(input io.Reader, s *Sampler) error {
+	return streamCsv(input, '\t', s)
+}
+
+func streamCsv(input io.Reader, comma rune, s *Sampler) error {
+	r := csv.NewReader(input)
+	r.Comma = comma
+	r.LazyQuotes = comma == '\t'
+	header, err := r.Read()
+	if err == io.EOF {
+		return &SyntaxError{Reason: "no header row"}
+	} else if err != nil {
+		return csvError(err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	r.ReuseRecord = true
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return csvError(err)
+		}
+		s.BeginObject()
+		for i, key := range header {
+			s.Key(key)
+			if row[i] == "" {
+				s.Null()
+			} else {
+				s.Scalar(csvCell(row[i]))
+			}
+		}
+		s.EndObject()
+	}
+}
+
+// A csvCell is the text of a non-empty CSV cell, whose type is that of its
+// column, known once all of its cells have been seen, see csvColumn.
+type csvCell string
+
+// csvCellType is the type name of csvCell values in shapes.
+var csvCellType = typeName(csvCell(""))
+
+// A csvColumn tracks the types all the non-empty cells of a column parse
+// as, starting with all of them.
+type csvColumn struct {
+	notInt, notFloat, notBool, notTime bool
+}
+
+func (c *csvColumn) observe(cell string) {
+	if cell == "" {
+		return
+	}
+	if _, err := strconv.ParseInt(cell, 10, 64); err != nil {
+		c.notInt = true
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err != nil {
+		c.notFloat = true
+	}
+	if _, err := strconv.ParseBool(cell); err != nil {
+		c.notBool = true
+	}
+	if _, ok := parseCsvTime(cell); !ok {
+		c.notTime = true
+	}
+}
+
+// typeName returns the name of the Go type of the column.
+func (c *csvColumn) typeName() string {
+	switch {
+	case !c.notInt:
+		return "int64"
+	case !c.notFloat:
+		return "float64"
+	case !c.notBool:
+		return "bool"
+	case !c.notTime:
+		return "time.Time"
+	}
+	return "string"
+}
+
+// value converts cell to the type of the column.
+func (c *csvColumn) value(cell string) interface{} {
+	if cell == "" {
+		return nil
+	}
+	switch {
+	case !c.notInt:
+		n, _ := strconv.ParseInt(cell, 10, 64)
+		return n
+	case !c.notFloat:
+		f, _ := strconv.ParseFloat(cell, 64)
+		return f
+	case !c.notBool:
+		b, _ := strconv.ParseBool(cell)
+		return b
+	case !c.notTime:
+		t, _ := parseCsvTime(cell)
+		return t
+	}
+	return cell
+}
+
+// parseCsvTime parses a cell holding a date or an RFC 3339 timestamp.
+func parseCsvTime(cell string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, cell); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
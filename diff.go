@@ -0,0 +1,75 @@
+package gojson
+
+import (
+	"sort"
+)
+
+// Diff lists the fields that differ between old and m, one per line and
+// in order, as "+ User.Email string" for fields m adds, "- User.Age int64"
+// for fields it removes and "~ User.ID int64 -> string" for fields whose
+// type changed. Fields of inline structs are named after the field
+// holding them, e.g. "User.Address.City".
+func (m *Model) Diff(old *Model) []string {
+	before, after := old.fields(), m.fields()
+	var diff []string
+	for name, t := range after {
+		switch prev, ok := before[name]; {
+		case !ok:
+			diff = append(diff, "+ "+name+" "+t)
+		case prev != t:
+			diff = append(diff, "~ "+name+" "+prev+" -> "+t)
+		}
+	}
+	for name, t := range before {
+		if _, ok := after[name]; !ok {
+			diff = append(diff, "- "+name+" "+t)
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i][2:] < diff[j][2:]
+	})
+	return diff
+}
+
+// fields maps the qualified name of every field of m to its type.
+func (m *Model) fields() map[string]string {
+	fields := make(map[string]string)
+	for _, d := range m.Types {
+		if d.Type.Struct == nil || d.Alias {
+			addFields(fields, d.Name, d.Type)
+		}
+	}
+	for _, s := range m.Structs {
+		for _, f := range s.Fields {
+			addFields(fields, s.Name+"."+f.Name, f.Type)
+		}
+	}
+	return fields
+}
+
+// addFields adds a field called name of type t to fields, along with the
+// fields of t if it is an inline struct or a slice or map of one.
+func addFields(fields map[string]string, name string, t *Type) {
+	fields[name] = summary(t)
+	for t.Elem != nil {
+		t = t.Elem
+	}
+	if t.Struct != nil && t.Struct.Name == "" {
+		for _, f := range t.Struct.Fields {
+			addFields(fields, name+"."+f.Name, f.Type)
+		}
+	}
+}
+
+// summary returns t as Go source, with inline structs left out.
+func summary(t *Type) string {
+	switch {
+	case t.Key != nil:
+		return "map[" + summary(t.Key) + "]" + summary(t.Elem)
+	case t.Elem != nil:
+		return "[]" + summary(t.Elem)
+	case t.Struct != nil && t.Struct.Name == "":
+		return "struct{...}"
+	}
+	return t.String()
+}
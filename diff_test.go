@@ -0,0 +1,33 @@
+package gojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDiff tests that the fields added, removed and changed between two models are listed
+func TestDiff(t *testing.T) {
+	infer := func(in string) *Model {
+		m, err := Infer(strings.NewReader(in), ParseJson, Options{Name: "User", ConvertFloats: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	old := infer(`{"id": 1, "name": "a", "address": {"city": "x"}}`)
+	m := infer(`{"id": "1", "address": {"city": "x", "zip": 12345}, "tags": ["a"]}`)
+
+	expected := []string{
+		"+ User.Address.Zip int64",
+		"~ User.ID int64 -> string",
+		"- User.Name string",
+		"+ User.Tags []string",
+	}
+	if actual := m.Diff(old); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q, but expected %q", actual, expected)
+	}
+	if actual := m.Diff(m); len(actual) != 0 {
+		t.Errorf("got %q for identical models", actual)
+	}
+}
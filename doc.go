@@ -0,0 +1,129 @@
+package gojson
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxExampleLen is the number of characters of a string sample shown in
+// doc comments.
+const maxExampleLen = 40
+
+var (
+	uuidRegexp  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// fieldDoc describes the values observed for a field that was seen in an
+// object samples times.
+func fieldDoc(s *shape, samples int) string {
+	numbers, strs := s.numbers, s.strings
+	if s.name == csvCellType {
+		// cells are numbers or strings as their column is
+		switch s.cells.typeName() {
+		case "int64", "float64":
+			strs = 0
+		case "string":
+			numbers = 0
+		default:
+			numbers, strs = 0, 0
+		}
+	}
+
+	parts := make([]string, 0)
+	if s.kind == kindScalar && s.sample != nil {
+		parts = append(parts, "e.g. "+example(s.example()))
+	}
+	parts = append(parts, fmt.Sprintf("seen in %d/%d samples", s.seen, samples))
+	if s.nulls > 0 {
+		parts = append(parts, fmt.Sprintf("null in %d", s.nulls))
+	}
+	if s.kind == kindScalar && numbers > 0 {
+		parts = append(parts, fmt.Sprintf("range [%v, %v]", s.min, s.max))
+	}
+	if s.kind == kindScalar && strs > 0 && s.format != "" {
+		parts = append(parts, "format "+s.format)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// example formats a sample value for a doc comment, truncating long strings.
+func example(v interface{}) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		if len(v) > maxExampleLen {
+			v = v[:maxExampleLen]
+		}
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	if runes := []rune(str); len(runes) > maxExampleLen {
+		return strconv.Quote(string(runes[:maxExampleLen])) + "..."
+	}
+	return strconv.Quote(str)
+}
+
+// stringFormat returns the name of a well known format str is in, such as
+// "date-time" or "uuid", or "" if it is in none.
+func stringFormat(str string) string {
+	if _, err := time.Parse(time.RFC3339, str); err == nil {
+		return "date-time"
+	}
+	if _, err := time.Parse("2006-01-02", str); err == nil {
+		return "date"
+	}
+	if uuidRegexp.MatchString(str) {
+		return "uuid"
+	}
+	if emailRegexp.MatchString(str) {
+		return "email"
+	}
+	if u, err := url.Parse(str); err == nil && u.Scheme != "" && u.Host != "" {
+		return "uri"
+	}
+	if _, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return "integer"
+	}
+	return ""
+}
+
+// toFloat converts a numeric scalar to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,276 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// A SyntaxError reports malformed input and where it was found.
+type SyntaxError struct {
+	File   string // name of the input, if known
+	Line   int    // 1-based line, 0 if unknown
+	Column int    // 1-based column, 0 if unknown
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return position(e.File, e.Line, e.Column) + ": " + e.Reason
+}
+
+// A PathError reports a value of the input that no Go type can be
+// generated for.
+type PathError struct {
+	File   string // name of the input, if known
+	Path   string // JSON path of the value, e.g. "$.items[*].id"
+	Reason string
+}
+
+func (e *PathError) Error() string {
+	return position(e.File, 0, 0) + ": " + e.Path + ": " + e.Reason
+}
+
+// An InternalError reports that gojson generated invalid Go source, which
+// is a bug in gojson.
+type InternalError struct {
+	Path  string // JSON path of the value the invalid source was generated for
+	Err   error  // the error reported by go/format
+	Repro string // a minimal JSON input that reproduces the bug
+}
+
+func (e *InternalError) Error() string {
+	msg := fmt.Sprintf("internal error: invalid Go source generated for %s: %s", e.Path, e.Err)
+	if e.Repro != "" {
+		msg += "\nplease report this bug at https://github.com/ChimeraCoder/gojson/issues with the input\n\t" + e.Repro
+	}
+	return msg
+}
+
+func position(file string, line, column int) string {
+	if file == "" {
+		file = "input"
+	}
+	if line > 0 {
+		file += ":" + strconv.Itoa(line)
+		if column > 0 {
+			file += ":" + strconv.Itoa(column)
+		}
+	}
+	return file
+}
+
+// withFile names the input of syntax and path errors.
+func withFile(err error, file string) error {
+	switch err := err.(type) {
+	case *SyntaxError:
+		if err.File == "" {
+			err.File = file
+		}
+	case *PathError:
+		if err.File == "" {
+			err.File = file
+		}
+	}
+	return err
+}
+
+// maxWindow is the number of most recently read bytes a positionReader
+// keeps to compute columns.
+const maxWindow = 1 << 20
+
+// positionReader tracks the line and column of offsets into its input,
+// keeping only a bounded window of recently read bytes.
+type positionReader struct {
+	r           io.Reader
+	window      []byte
+	windowStart int64 // offset of window[0]
+	windowLine  int   // line of window[0]
+	windowCol   int   // column of window[0]
+}
+
+func newPositionReader(r io.Reader) *positionReader {
+	return &positionReader{r: r, windowLine: 1, windowCol: 1}
+}
+
+func (p *positionReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.window = append(p.window, b[:n]...)
+	if drop := len(p.window) - maxWindow; drop > 0 {
+		p.advance(p.window[:drop])
+		p.window = append(p.window[:0], p.window[drop:]...)
+	}
+	return n, err
+}
+
+// advance moves the start of the window past b.
+func (p *positionReader) advance(b []byte) {
+	p.windowStart += int64(len(b))
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		p.windowLine += bytes.Count(b, []byte{'\n'})
+		p.windowCol = len(b) - i
+	} else {
+		p.windowCol += len(b)
+	}
+}
+
+// position returns the line and column of a byte offset, or zeroes if the
+// offset has left the window.
+func (p *positionReader) position(offset int64) (line, column int) {
+	i := offset - p.windowStart
+	if i < 0 || i > int64(len(p.window)) {
+		return 0, 0
+	}
+	b := p.window[:i]
+	if j := bytes.LastIndexByte(b, '\n'); j >= 0 {
+		return p.windowLine + bytes.Count(b, []byte{'\n'}), len(b) - j
+	}
+	return p.windowLine, p.windowCol + len(b)
+}
+
+// since returns the input read from offset on, if it is still in the
+// window.
+func (p *positionReader) since(offset int64) ([]byte, bool) {
+	i := offset - p.windowStart
+	if i < 0 || i > int64(len(p.window)) {
+		return nil, false
+	}
+	return p.window[i:], true
+}
+
+// end returns the position just past the input read so far.
+func (p *positionReader) end() (line, column int) {
+	return p.position(p.windowStart + int64(len(p.window)))
+}
+
+// jsonError converts an error from encoding/json into a SyntaxError.
+func (p *positionReader) jsonError(err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return p.jsonSyntaxError(e, 0)
+	}
+	switch err {
+	case io.EOF:
+		return &SyntaxError{Reason: "empty input"}
+	case io.ErrUnexpectedEOF:
+		line, column := p.end()
+		return &SyntaxError{Line: line, Column: column, Reason: "unexpected end of input"}
+	}
+	return err
+}
+
+// jsonSyntaxError converts a syntax error in the input read from offset
+// start on into a SyntaxError.
+func (p *positionReader) jsonSyntaxError(e *json.SyntaxError, start int64) error {
+	if e.Error() == "unexpected end of JSON input" {
+		return p.jsonError(io.ErrUnexpectedEOF)
+	}
+	// Offset is just past the offending byte
+	line, column := p.position(start + e.Offset - 1)
+	return &SyntaxError{Line: line, Column: column, Reason: e.Error()}
+}
+
+var yamlErrorRegexp = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlParserErrors are the errors the YAML parser, as opposed to its
+// scanner, reports with 0-based lines.
+var yamlParserErrors = map[string]bool{
+	"did not find expected <stream-start>":   true,
+	"did not find expected <document start>": true,
+	"found undefined tag handle":             true,
+	"did not find expected node content":     true,
+	"did not find expected '-' indicator":    true,
+	"did not find expected key":              true,
+	"did not find expected ',' or ']'":       true,
+	"did not find expected ',' or '}'":       true,
+	"found duplicate %YAML directive":        true,
+	"found incompatible YAML document":       true,
+	"found duplicate %TAG directive":         true,
+}
+
+// yamlError converts an error from decoding YAML into a SyntaxError.
+func yamlError(err error) error {
+	msg := err.Error()
+	if m := yamlErrorRegexp.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		if yamlParserErrors[m[2]] {
+			line++
+		}
+		return &SyntaxError{Line: line, Reason: m[2]}
+	}
+	if strings.HasPrefix(msg, "yaml: ") {
+		return &SyntaxError{Reason: strings.TrimPrefix(msg, "yaml: ")}
+	}
+	return err
+}
+
+// tomlError converts a TOML parse error into a SyntaxError.
+func tomlError(err error) error {
+	if e, ok := err.(toml.ParseError); ok {
+		return &SyntaxError{Line: e.Position.Line, Column: e.Position.Col, Reason: e.Message}
+	}
+	return err
+}
+
+// csvError converts a CSV parse error into a SyntaxError.
+func csvError(err error) error {
+	if e, ok := err.(*csv.ParseError); ok {
+		return &SyntaxError{Line: e.Line, Column: e.Column, Reason: e.Err.Error()}
+	}
+	return err
+}
+
+// xmlError converts an XML syntax error into a SyntaxError.
+func xmlError(err error) error {
+	if e, ok := err.(*xml.SyntaxError); ok {
+		return &SyntaxError{Line: e.Line, Reason: e.Msg}
+	}
+	return err
+}
+
+// internalError locates the field of m that go/format rejected with err.
+func (m *Model) internalError(err error) error {
+	// top-level types may themselves be slices of inline structs
+	var structs []*Struct
+	for _, d := range m.Types {
+		structs = append(structs, &Struct{Fields: []*Field{{Type: d.Type}}})
+	}
+	structs = append(structs, m.Structs...)
+	for _, s := range structs {
+		if f := culprit(s); f != nil {
+			repro, _ := json.Marshal(f.path.repro(f.sample))
+			return &InternalError{Path: f.Path, Err: err, Repro: string(repro)}
+		}
+	}
+	return &InternalError{Path: "$", Err: err}
+}
+
+// culprit returns the innermost field of s that is invalid Go source on
+// its own, or nil.
+func culprit(s *Struct) *Field {
+	for _, f := range s.Fields {
+		for t := f.Type; t != nil; t = t.Elem {
+			if t.Struct != nil && t.Struct.Name == "" {
+				if c := culprit(t.Struct); c != nil {
+					return c
+				}
+			}
+		}
+		if f.Name == "" {
+			continue
+		}
+		src := fmt.Sprintf("package p\ntype _ struct {\n%s%s int `%s`\n}", comment(f.Doc), f.Name, f.Tag)
+		if _, err := format.Source([]byte(src)); err != nil {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package gojson
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestSyntaxErrorPosition tests that syntax errors report the line and column they were found at
+func TestSyntaxErrorPosition(t *testing.T) {
+	examples := []struct {
+		Parser       Parser
+		StreamParser StreamParser
+		In           string
+		Line, Column int
+	}{
+		{Parser: ParseJson, StreamParser: StreamJson, In: "{\n  \"a\": 1,\n  \"b\": x\n}", Line: 3, Column: 8},
+		{Parser: ParseJson, StreamParser: StreamJson, In: "[1,\n2", Line: 2, Column: 2},
+		{Parser: ParseJson, StreamParser: StreamJson, In: "{\n  \"a\": 1,\n  \"bb\": [1,]\n}", Line: 3, Column: 12},
+		{Parser: ParseJson, StreamParser: StreamJson, In: "{\"a\": 1, \"b\": {\"c\": 2,}}", Line: 1, Column: 23},
+		{Parser: ParseYaml, StreamParser: StreamYaml, In: "a: 1\nb: [1\nc: 2\n", Line: 2},
+	}
+
+	for i, ex := range examples {
+		_, err := Infer(strings.NewReader(ex.In), ex.Parser, Options{Name: "Test", Source: "test"})
+		_, serr := InferStream(strings.NewReader(ex.In), ex.StreamParser, Options{Name: "Test", Source: "test"})
+		for _, err := range []error{err, serr} {
+			e, ok := err.(*SyntaxError)
+			if !ok {
+				t.Errorf("[Example %d] expected a *SyntaxError, got %#v", i+1, err)
+				continue
+			}
+			if e.File != "test" || e.Line != ex.Line || e.Column != ex.Column {
+				t.Errorf("[Example %d] got %s, but expected test:%d:%d", i+1, e, ex.Line, ex.Column)
+			}
+		}
+		if err != nil && serr != nil && err.Error() != serr.Error() {
+			t.Errorf("[Example %d] streaming reported '%s', but decoding whole reported '%s'", i+1, serr, err)
+		}
+	}
+}
+
+// TestSyntaxErrorPositionYamlStream tests that positions in later documents of a YAML stream are relative to the stream
+func TestSyntaxErrorPositionYamlStream(t *testing.T) {
+	_, single := Infer(strings.NewReader("a: 1\nb: 1\n c: 2: 3\n"), ParseYaml, Options{Name: "Test"})
+	_, stream := InferStream(strings.NewReader("x: 1\n---\na: 1\nb: 1\n c: 2: 3\n"), StreamYaml, Options{Name: "Test"})
+	e1, ok1 := single.(*SyntaxError)
+	e2, ok2 := stream.(*SyntaxError)
+	if !ok1 || !ok2 {
+		t.Fatalf("expected *SyntaxErrors, got %#v and %#v", single, stream)
+	}
+	if e2.Line != e1.Line+2 {
+		t.Errorf("got line %d in the stream, but expected %d", e2.Line, e1.Line+2)
+	}
+}
+
+// TestPathError tests that values no type can be generated for are reported with their JSON path
+func TestPathError(t *testing.T) {
+	examples := []struct {
+		In   string
+		Path string
+	}{
+		{In: `42`, Path: "$"},
+		{In: `{"items": [{"user_id": 1, "userId": 2}]}`, Path: "$.items[*].user_id"},
+	}
+
+	for i, ex := range examples {
+		_, err := Infer(strings.NewReader(ex.In), ParseJson, Options{Name: "Test", Source: "test"})
+		e, ok := err.(*PathError)
+		if !ok {
+			t.Errorf("[Example %d] expected a *PathError, got %#v", i+1, err)
+			continue
+		}
+		if e.File != "test" || e.Path != ex.Path {
+			t.Errorf("[Example %d] got %s, but expected path %s", i+1, e, ex.Path)
+		}
+	}
+}
+
+// TestInternalError tests that invalid generated source is reported with a minimal reproduction
+func TestInternalError(t *testing.T) {
+	m, err := Infer(strings.NewReader(`{"ok": 1, "list": [{"nested": {"bad`+"`"+`key": "v"}}]}`), ParseJson, Options{Name: "Test", Package: "gojson", Tags: []string{"json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Source()
+	e, ok := err.(*InternalError)
+	if !ok {
+		t.Fatalf("expected an *InternalError, got %#v", err)
+	}
+	if e.Path != "$.list[*].nested[\"bad`key\"]" {
+		t.Errorf("got path %s", e.Path)
+	}
+	if e.Repro != `{"list":[{"nested":{"bad`+"`"+`key":"v"}}]}` {
+		t.Errorf("got reproduction %s", e.Repro)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+// TestReadError tests that I/O errors are not mistaken for empty input
+func TestReadError(t *testing.T) {
+	for _, parser := range []Parser{ParseJson, ParseYaml} {
+		_, err := Infer(io.MultiReader(strings.NewReader(`{"a": `), failingReader{}), parser, Options{Name: "Test"})
+		if err == nil || err.Error() != "read failed" {
+			t.Errorf("expected the read error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package gojson
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Format is an input format, and how gojson reads it.
+type Format struct {
+	Name         string
+	Parser       Parser       // nil if the format is only read as a stream
+	StreamParser StreamParser // nil if the format cannot be streamed
+	Tag          string       // struct tag fields get unless others are asked for
+
+	// ConvertFloats is set for formats whose numbers are untyped, so
+	// that those that look integral become int64.
+	ConvertFloats bool
+
+	// parse parses as Parser does, into the values Infer samples rather
+	// than plain ones, if they differ.
+	parse Parser
+}
+
+// Formats are the input formats gojson reads.
+var Formats = []Format{
+	{Name: "json", Parser: ParseJson, StreamParser: StreamJson, Tag: "json", ConvertFloats: true},
+	{Name: "json5", Parser: ParseJson5, Tag: "json", ConvertFloats: true, parse: parseJson5},
+	{Name: "yaml", Parser: ParseYaml, StreamParser: StreamYaml, Tag: "yaml", parse: parseYaml},
+	{Name: "toml", Parser: ParseToml, Tag: "toml"},
+	{Name: "xml", Parser: ParseXml, Tag: "xml"},
+	// every row is a sample, so CSV is always streamed
+	{Name: "csv", StreamParser: StreamCsv, Tag: "csv"},
+	{Name: "tsv", StreamParser: StreamTsv, Tag: "csv"},
+	{Name: "har", Parser: ParseHar, Tag: "json", ConvertFloats: true, parse: parseHar},
+	{Name: "http", Parser: ParseHttp, Tag: "json", ConvertFloats: true, parse: parseHttp},
+}
+
+// LookupFormat returns the format called name.
+func LookupFormat(name string) (Format, bool) {
+	for _, f := range Formats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// FormatNames lists the names of Formats for messages, e.g. "json, yaml
+// or toml".
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = f.Name
+	}
+	last := len(names) - 1
+	return strings.Join(names[:last], ", ") + " or " + names[last]
+}
+
+// sampleInput reports the values of input, read in format f, to s.
+func sampleInput(s *Sampler, input io.Reader, f Format) error {
+	if f.Parser == nil {
+		return f.StreamParser(input, s)
+	}
+	parse := f.parse
+	if parse == nil {
+		parse = f.Parser
+	}
+	v, err := parse(input)
+	if err != nil {
+		return err
+	}
+	s.sample(v)
+	return nil
+}
+
+// InferFormat reads input in format f and infers a Model of the Go types
+// that describe it. Unlike Infer with f.Parser, it samples what the format
+// holds beyond plain values, such as YAML maps with integer keys, the
+// comments of JSON5 keys or the exchanges of a HAR archive, each typed
+// after its URL. Input is streamed if f has no Parser.
+func InferFormat(input io.Reader, f Format, opts Options) (*Model, error) {
+	s, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := sampleInput(s, input, f); err != nil {
+		return nil, withFile(err, opts.Source)
+	}
+	return s.Model()
+}
+
+// InputFiles returns the files an input named name consists of: the file
+// itself, or the files of a directory, in order, other than hidden ones
+// and subdirectories.
+func InputFiles(name string) ([]string, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{name}, nil
+	}
+	infos, err := ioutil.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, fi := range infos {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(name, fi.Name()))
+	}
+	return files, nil
+}
+
+// InferFiles reads the files of InputFiles(name) in format f, merging each
+// as another sample, and infers a Model of the Go types that describe them.
+// Files are streamed if f has no Parser.
+func InferFiles(name string, f Format, opts Options) (*Model, error) {
+	files, err := InputFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		in, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		err = sampleInput(s, in, f)
+		in.Close()
+		if err != nil {
+			return nil, withFile(err, file)
+		}
+	}
+	return s.Model()
+}
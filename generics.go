@@ -0,0 +1,184 @@
+package gojson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goMinor returns the minor version of Go opts.GoVersion names, e.g. 18
+// for "1.18" or "go1.18.2", or 0 if it is empty.
+func (opts Options) goMinor() (int, error) {
+	if opts.GoVersion == "" {
+		return 0, nil
+	}
+	v := strings.TrimPrefix(opts.GoVersion, "go")
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, fmt.Errorf("invalid Go version %q", opts.GoVersion)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid Go version %q", opts.GoVersion)
+	}
+	return minor, nil
+}
+
+// generics reports whether the generated source may use generic types.
+func (opts Options) generics() bool {
+	minor, _ := opts.goMinor()
+	return minor >= 18
+}
+
+// An envelope is a set of top-level structs that differ only in the type
+// of one field, their payload.
+type envelope struct {
+	key     string  // fields of the structs, the payload's type left out
+	payload int     // index of the payload field
+	members []*Decl // the types declared as the structs
+}
+
+// extractEnvelopes declares the top-level structs of m that differ only in
+// the type of one field, such as the data of the responses of an API, as
+// instantiations of a generic struct with a type parameter for that field,
+// e.g. "type GetUsersResponse = Response[[]User]". The largest sets of
+// structs are declared so first.
+func (b *modelBuilder) extractEnvelopes(m *Model) {
+	byKey := make(map[string]*envelope)
+	for _, d := range m.Types {
+		st := d.Type.Struct
+		if st == nil || st.Name != d.Name || len(st.Fields) < 2 {
+			continue
+		}
+		for i := range st.Fields {
+			sigs := make([]string, len(st.Fields))
+			for j, f := range st.Fields {
+				sigs[j] = f.signature()
+				if j == i {
+					sigs[j] = f.Name + " * `" + f.Tag + "`"
+				}
+			}
+			key := strings.Join(sigs, "\n")
+			if byKey[key] == nil {
+				byKey[key] = &envelope{key: key, payload: i}
+			}
+			byKey[key].members = append(byKey[key].members, d)
+		}
+	}
+	envelopes := make([]*envelope, 0, len(byKey))
+	for _, e := range byKey {
+		envelopes = append(envelopes, e)
+	}
+	sort.Slice(envelopes, func(i, j int) bool {
+		a, b := envelopes[i], envelopes[j]
+		if len(a.members) != len(b.members) {
+			return len(a.members) > len(b.members)
+		}
+		return a.key < b.key
+	})
+
+	taken := make(map[string]bool)
+	for _, d := range m.Types {
+		taken[d.Name] = true
+	}
+	for _, st := range m.Structs {
+		taken[st.Name] = true
+	}
+	used := make(map[*Decl]bool)
+	for _, e := range envelopes {
+		var members []*Decl
+		payloads := make(map[string]bool)
+		for _, d := range e.members {
+			if !used[d] {
+				members = append(members, d)
+				payloads[d.Type.Struct.Fields[e.payload].Type.String()] = true
+			}
+		}
+		if len(members) < 2 || len(payloads) < 2 {
+			continue
+		}
+
+		names := make([]string, len(members))
+		for i, d := range members {
+			names[i] = d.Name
+		}
+		base := commonSuffix(names)
+		if base == "" {
+			base = "Envelope"
+		}
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%v%v", base, i)
+		}
+		taken[name] = true
+
+		generic := &Struct{Name: name, Params: []string{"T"}}
+		if b.opts.Comments {
+			generic.Doc = fmt.Sprintf("%s is the envelope of %s.", name, strings.Join(names, ", "))
+		}
+		for i, f := range members[0].Type.Struct.Fields {
+			field := *f
+			if i == e.payload {
+				field.Type, field.Doc = &Type{Name: "T"}, ""
+			}
+			generic.Fields = append(generic.Fields, &field)
+		}
+
+		// the generic struct takes the place of the first of its members
+		replaced := make(map[*Struct]*Struct)
+		for i, d := range members {
+			used[d] = true
+			st := d.Type.Struct
+			replaced[st] = nil
+			if i == 0 {
+				replaced[st] = generic
+			}
+			d.Type = &Type{Struct: generic, Args: []*Type{st.Fields[e.payload].Type}}
+			d.Alias = true
+		}
+		structs := m.Structs[:0]
+		for _, st := range m.Structs {
+			if r, ok := replaced[st]; ok {
+				st = r
+			}
+			if st != nil {
+				structs = append(structs, st)
+			}
+		}
+		m.Structs = structs
+	}
+}
+
+// commonSuffix returns the words, as in CamelCase, that every one of names
+// ends with, e.g. "Response" for GetUsersResponse and GetReposResponse.
+func commonSuffix(names []string) string {
+	suffix := camelWords(names[0])
+	for _, name := range names[1:] {
+		words := camelWords(name)
+		n := 0
+		for n < len(suffix) && n < len(words) && suffix[len(suffix)-1-n] == words[len(words)-1-n] {
+			n++
+		}
+		suffix = suffix[len(suffix)-n:]
+	}
+	return strings.Join(suffix, "")
+}
+
+// camelWords splits a CamelCase name into its words, keeping initialisms
+// whole, e.g. "Get", "User", "ID", "Response" for GetUserIDResponse.
+func camelWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		r, prev := runes[i], runes[i-1]
+		next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(r) && (!unicode.IsUpper(prev) || next) || r == '_' {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
@@ -0,0 +1,28 @@
+package gojson
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEnvelopes tests that top-level types differing only in their payload are declared as instantiations of a generic struct
+func TestEnvelopes(t *testing.T) {
+	opts := Options{Package: "api", Tags: []string{"json"}, GoVersion: "1.18"}
+	expectSource(t, sessionModel(t, "envelopes", opts), "expected_envelopes.go.out")
+
+	opts.GoVersion = "1.17"
+	src, err := sessionModel(t, "envelopes", opts).Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "[T any]") {
+		t.Errorf("expected no generic types before Go 1.18, got '%s'", src)
+	}
+
+	opts.GoVersion = "one"
+	s := NewSession(opts)
+	f, _ := LookupFormat("json")
+	if err := s.Add("Config", "", f, "Config.json", strings.NewReader(`{"debug": true}`)); err == nil {
+		t.Error("expected an error for an invalid Go version")
+	}
+}
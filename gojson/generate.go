@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	. "github.com/ChimeraCoder/gojson"
+)
+
+// generateMain runs the generate subcommand, which generates every type
+// listed in a manifest, e.g. from a single //go:generate gojson generate
+// line.
+func generateMain(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	manifest := fs.String("manifest", "gojson.yaml", "the manifest listing the types to generate")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gojson generate [-manifest gojson.yaml]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	m, err := ReadManifest(*manifest)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sources, err := m.Generate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error parsing", err)
+		os.Exit(1)
+	}
+
+	outputs := make([]string, 0, len(sources))
+	for output := range sources {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+	for _, output := range outputs {
+		// leave unchanged files alone, so that build tools don't see them change
+		if old, err := ioutil.ReadFile(output); err == nil && bytes.Equal(old, sources[output]) {
+			continue
+		}
+		if err := ioutil.WriteFile(output, sources[output], 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "writing output:", err)
+			os.Exit(1)
+		}
+	}
+}
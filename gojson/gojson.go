@@ -45,83 +45,250 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	. "github.com/ChimeraCoder/gojson"
 )
 
 var (
-	name        = flag.String("name", "Foo", "the name of the struct")
-	pkg         = flag.String("pkg", "main", "the name of the package for the generated code")
-	inputName   = flag.String("input", "", "the name of the input file containing JSON (if input not provided via STDIN)")
-	outputName  = flag.String("o", "", "the name of the file to write the output to (outputs to STDOUT by default)")
-	format      = flag.String("fmt", "json", "the format of the input data (json or yaml, defaults to json)")
-	tags        = flag.String("tags", "fmt", "comma seperated list of the tags to put on the struct, default is the same as fmt")
-	forceFloats = flag.Bool("forcefloats", false, "[experimental] force float64 type for integral values")
-	subStruct   = flag.Bool("subStruct", false, "create types for sub-structs (default is false)")
+	name          = flag.String("name", "Foo", "the name of the struct")
+	pkg           = flag.String("pkg", "main", "the name of the package for the generated code")
+	inputName     = flag.String("input", "", "the name of the input file containing JSON (if input not provided via STDIN)")
+	outputName    = flag.String("o", "", "the name of the file to write the output to (outputs to STDOUT by default)")
+	format        = flag.String("fmt", "json", "the format of the input data (json, json5, yaml, toml, xml, csv, tsv, har or http, defaults to json)")
+	tags          = flag.String("tags", "fmt", "comma seperated list of the tags to put on the struct, default is the same as fmt")
+	numbers       = flag.String("numbers", "int64", "the types of numbers in formats that do not tell them, such as JSON: int64 (or float64 for non-integral ones), smallest (the smallest int or uint type that fits) or float64")
+	placeholder   = flag.String("placeholder", "interface", "the types of arrays and objects empty in every sample: interface ([]interface{} and struct{}), raw (json.RawMessage), map (map[string]any), todo (as interface, with a TODO comment) or named (a type Unknown); their locations are listed on stderr")
+	forceFloats   = flag.Bool("forcefloats", false, "deprecated: use -numbers float64")
+	subStruct     = flag.Bool("subStruct", false, "create types for sub-structs (default is false)")
+	comments      = flag.Bool("comments", false, "add doc comments with sample values and statistics to the generated fields")
+	stream        = flag.Bool("stream", false, "read the input incrementally with bounded memory; every JSON value or YAML document is a sample")
+	maxElements   = flag.Int("maxElements", 0, "inspect at most this many elements of each array (0 for all)")
+	reservoir     = flag.Bool("reservoir", false, "choose the -maxElements elements of each array at random rather than the first ones")
+	every         = flag.Int("every", 0, "inspect only every n-th element of each array")
+	maxDepth      = flag.Int("maxDepth", 0, "inspect at most this many levels of nested objects and arrays (0 for all)")
+	maxKeys       = flag.Int("maxKeys", 0, "inspect at most this many distinct keys of each object (0 for all)")
+	tmplName      = flag.String("template", "", "the name of a text/template file to render the inferred types with (implies -subStruct)")
+	watchInput    = flag.Bool("watch", false, "keep running, and regenerate the output whenever the -input file or directory changes")
+	watchInterval = flag.Duration("watchInterval", time.Second, "how often -watch checks the input for changes")
+	bases         = flag.Float64("bases", 0, "extract fields several structs share into embedded base structs, where they make up at least this share (0 to 1) of each struct's fields")
+	basesDryRun   = flag.Bool("basesDryRun", false, "only report the base structs -bases would extract, on stderr")
+	goVersion     = flag.String("go", "", "the Go version the output targets, e.g. 1.18, from which on response envelopes that differ only in their payload become a generic type")
+	groupBy       = flag.String("groupBy", "", "comma separated list of discriminator keys, e.g. kind,apiVersion, to generate a type for each kind of document in a stream")
+	paths         selections
 )
 
+func init() {
+	flag.Var(&paths, "path", "a JSONPath expression, e.g. '$.data.items[*]', selecting the values to generate a type for; repeat as Name=expr to generate several types")
+}
+
+// selections collects the values of the repeatable -path flag.
+type selections []Selection
+
+func (s *selections) String() string {
+	exprs := make([]string, len(*s))
+	for i, sel := range *s {
+		exprs[i] = sel.Path
+		if sel.Name != "" {
+			exprs[i] = sel.Name + "=" + sel.Path
+		}
+	}
+	return strings.Join(exprs, ",")
+}
+
+func (s *selections) Set(value string) error {
+	var sel Selection
+	if i := strings.Index(value, "="); i > 0 && !strings.HasPrefix(value, "$") {
+		sel.Name, value = value[:i], value[i+1:]
+	}
+	sel.Path = value
+	*s = append(*s, sel)
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "proxy":
+			proxyMain(os.Args[2:])
+			return
+		case "serve":
+			serveMain(os.Args[2:])
+			return
+		case "generate":
+			generateMain(os.Args[2:])
+			return
+		}
+	}
 	flag.Parse()
 
-	if *format != "json" && *format != "yaml" {
+	inputFormat, ok := LookupFormat(*format)
+	if !ok {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, "fmt must be "+FormatNames())
+		os.Exit(1)
+	}
+
+	if *stream && inputFormat.StreamParser == nil {
+		flag.Usage()
+		fmt.Fprintf(os.Stderr, "-stream is not supported for %s\n", *format)
+		os.Exit(1)
+	}
+
+	var numberPolicy NumberPolicy
+	if err := numberPolicy.UnmarshalText([]byte(*numbers)); err != nil {
+		flag.Usage()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *forceFloats {
+		numberPolicy = FloatNumbers
+	}
+	var placeholderType Placeholder
+	if err := placeholderType.UnmarshalText([]byte(*placeholder)); err != nil {
 		flag.Usage()
-		fmt.Fprintln(os.Stderr, "fmt must be json or yaml")
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
 	tagList := make([]string, 0)
 	if tags == nil || *tags == "" || *tags == "fmt" {
-		tagList = append(tagList, *format)
+		tagList = append(tagList, inputFormat.Tag)
 	} else {
 		tagList = strings.Split(*tags, ",")
 	}
 
+	if len(paths) > 1 {
+		for _, sel := range paths {
+			if sel.Name == "" {
+				flag.Usage()
+				fmt.Fprintln(os.Stderr, "-path must be given as Name=expr when repeated")
+				os.Exit(1)
+			}
+		}
+	}
+
 	if isInteractive() && *inputName == "" {
 		flag.Usage()
 		fmt.Fprintln(os.Stderr, "Expects input on stdin")
 		os.Exit(1)
 	}
 
-	var input io.Reader
-	input = os.Stdin
+	source := "stdin"
 	if *inputName != "" {
-		f, err := os.Open(*inputName)
-		if err != nil {
-			log.Fatalf("reading input file: %s", err)
+		source = *inputName
+	}
+	if *stream {
+		// InferFiles streams formats without a Parser
+		inputFormat.Parser = nil
+	}
+
+	opts := Options{
+		Name:          *name,
+		Package:       *pkg,
+		Tags:          tagList,
+		SubStruct:     *subStruct || *tmplName != "",
+		ConvertFloats: inputFormat.ConvertFloats,
+		Numbers:       numberPolicy,
+		Placeholder:   placeholderType,
+		Comments:      *comments,
+		Source:        source,
+		Limits: Limits{
+			MaxElements: *maxElements,
+			Reservoir:   *reservoir,
+			Every:       *every,
+			MaxDepth:    *maxDepth,
+			MaxKeys:     *maxKeys,
+		},
+		Bases:     Bases{Threshold: *bases, DryRun: *basesDryRun},
+		GoVersion: *goVersion,
+	}
+	if *groupBy != "" {
+		opts.GroupBy = strings.Split(*groupBy, ",")
+	}
+	if len(paths) == 1 {
+		if paths[0].Name != "" {
+			opts.Name = paths[0].Name
 		}
-		defer f.Close()
-		input = f
+		opts.Path = paths[0].Path
+	} else {
+		opts.Selections = paths
 	}
 
-	var convertFloats bool
-	var parser Parser
-	switch *format {
-	case "json":
-		parser = ParseJson
-		convertFloats = true
-	case "yaml":
-		parser = ParseYaml
+	if *watchInput {
+		if *inputName == "" {
+			flag.Usage()
+			fmt.Fprintln(os.Stderr, "-watch requires -input")
+			os.Exit(1)
+		}
+		watch(*inputName, *watchInterval, func() (*Model, []byte, error) {
+			return generate(inputFormat, opts)
+		})
+		return
 	}
 
-	if output, err := Generate(input, parser, *name, *pkg, tagList, *subStruct, convertFloats); err != nil {
-		fmt.Fprintln(os.Stderr, "error parsing", err)
+	_, output, err := generate(inputFormat, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
-	} else {
-		if *outputName != "" {
-			err := ioutil.WriteFile(*outputName, output, 0644)
-			if err != nil {
-				log.Fatalf("writing output: %s", err)
-			}
-		} else {
-			fmt.Print(string(output))
+	}
+	if err := writeOutput(output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate infers the types of the input and renders them.
+func generate(inputFormat Format, opts Options) (*Model, []byte, error) {
+	var m *Model
+	var err error
+	switch {
+	case *inputName != "":
+		m, err = InferFiles(*inputName, inputFormat, opts)
+	default:
+		// InferFormat streams formats without a Parser
+		m, err = InferFormat(os.Stdin, inputFormat, opts)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing %s", err)
+	}
+	for _, u := range m.Unresolved {
+		fmt.Fprintln(os.Stderr, "unresolved", u)
+	}
+	if *basesDryRun {
+		for _, s := range m.Suggestions {
+			fmt.Fprintln(os.Stderr, "base", s)
 		}
 	}
 
+	if *tmplName == "" {
+		output, err := m.Source()
+		return m, output, err
+	}
+	tmpl, err := ParseTemplateFile(*tmplName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading template: %s", err)
+	}
+	output, err := m.ExecuteTemplate(tmpl)
+	if err != nil {
+		return nil, nil, fmt.Errorf("executing template: %s", err)
+	}
+	return m, output, nil
+}
+
+// writeOutput writes output to the -o file, or to stdout.
+func writeOutput(output []byte) error {
+	if *outputName == "" {
+		fmt.Print(string(output))
+		return nil
+	}
+	if err := ioutil.WriteFile(*outputName, output, 0644); err != nil {
+		return fmt.Errorf("writing output: %s", err)
+	}
+	return nil
 }
 
 // Return true if os.Stdin appears to be interactive
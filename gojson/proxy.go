@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	. "github.com/ChimeraCoder/gojson"
+)
+
+// proxyMain runs the proxy subcommand, which forwards requests to an
+// upstream server and infers types from the bodies it sees. The types are
+// written when it receives SIGHUP and when it shuts down on SIGINT or
+// SIGTERM, and served at -types meanwhile.
+func proxyMain(args []string) {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "the address to listen on")
+	upstream := fs.String("upstream", "", "the URL of the server to forward requests to, e.g. http://localhost:9000")
+	typesPath := fs.String("types", "/_gojson/types", "the path the proxy serves the types inferred so far at, instead of forwarding it (empty to forward every path)")
+	pkg := fs.String("pkg", "main", "the name of the package for the generated code")
+	outputName := fs.String("o", "", "the name of the file to write the types to (outputs to STDOUT by default)")
+	tags := fs.String("tags", "json", "comma seperated list of the tags to put on the struct")
+	subStruct := fs.Bool("subStruct", false, "create types for sub-structs (default is false)")
+	comments := fs.Bool("comments", false, "add doc comments with sample values and statistics to the generated fields")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gojson proxy -upstream URL [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	u, err := url.Parse(*upstream)
+	if *upstream == "" || err != nil || u.Scheme == "" || u.Host == "" {
+		fs.Usage()
+		fmt.Fprintln(os.Stderr, "-upstream must be an absolute URL")
+		os.Exit(1)
+	}
+
+	h, err := NewHttpSampler(Options{
+		Package:       *pkg,
+		Tags:          strings.Split(*tags, ","),
+		SubStruct:     *subStruct,
+		ConvertFloats: true,
+		Comments:      *comments,
+		Source:        "traffic to " + *upstream,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", Proxy(u, h, nil))
+	if *typesPath != "" {
+		mux.HandleFunc(*typesPath, func(w http.ResponseWriter, req *http.Request) {
+			src, err := source(h)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write(src)
+		})
+	}
+	server := &http.Server{Addr: *listen, Handler: mux}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				writeTypes(h, *outputName)
+				continue
+			}
+			// let the requests in flight finish, so that they are sampled
+			server.Shutdown(context.Background())
+			close(done)
+			return
+		}
+	}()
+
+	log.Printf("gojson: forwarding %s to %s", *listen, *upstream)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+	writeTypes(h, *outputName)
+}
+
+// source renders the types h inferred so far.
+func source(h *HttpSampler) ([]byte, error) {
+	m, err := h.Model()
+	if _, ok := err.(*SyntaxError); ok {
+		return nil, errors.New("no JSON or YAML bodies seen yet")
+	} else if err != nil {
+		return nil, err
+	}
+	return m.Source()
+}
+
+// writeTypes writes the types h inferred so far to the file outputName, or
+// to stdout.
+func writeTypes(h *HttpSampler, outputName string) {
+	src, err := source(h)
+	if err != nil {
+		log.Printf("gojson: %s", err)
+		return
+	}
+	if outputName == "" {
+		fmt.Print(string(src))
+		return
+	}
+	if err := ioutil.WriteFile(outputName, src, 0644); err != nil {
+		log.Printf("gojson: writing output: %s", err)
+	}
+}
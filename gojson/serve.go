@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	. "github.com/ChimeraCoder/gojson"
+)
+
+// serveMain runs the serve subcommand, which exposes gojson as an HTTP API
+// at /generate.
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "localhost:8080", "the address to listen on")
+	maxBytes := fs.Int64("maxBytes", 10<<20, "the largest request accepted, in bytes")
+	timeout := fs.Duration("timeout", 30*time.Second, "the longest a request may take, from reading it to writing the response")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gojson serve [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	mux := http.NewServeMux()
+	mux.Handle("/generate", http.TimeoutHandler(Handler(*maxBytes), *timeout, "request timed out"))
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: *timeout,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      *timeout + time.Second,
+		MaxHeaderBytes:    1 << 20,
+	}
+
+	log.Printf("gojson: serving on %s", *listen)
+	log.Fatal(server.ListenAndServe())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	. "github.com/ChimeraCoder/gojson"
+)
+
+// watch runs generate whenever the files of the input called name change,
+// checking them every interval, and writes its output if it differs from
+// the last one. The fields that changed are logged.
+func watch(name string, interval time.Duration, generate func() (*Model, []byte, error)) {
+	var last []byte
+	if *outputName != "" {
+		// the output of an earlier run need not be rewritten
+		last, _ = ioutil.ReadFile(*outputName)
+	}
+	var lastModel *Model
+	var lastState string
+	for ; ; time.Sleep(interval) {
+		state, err := inputState(name)
+		if err != nil {
+			if state != lastState {
+				log.Print(err)
+			}
+			lastState = state
+			continue
+		}
+		if state == lastState {
+			continue
+		}
+		lastState = state
+
+		m, output, err := generate()
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if lastModel != nil {
+			for _, line := range m.Diff(lastModel) {
+				fmt.Fprintln(os.Stderr, line)
+			}
+		}
+		lastModel = m
+		if bytes.Equal(output, last) {
+			continue
+		}
+		if err := writeOutput(output); err != nil {
+			log.Print(err)
+			continue
+		}
+		last = output
+		if *outputName != "" {
+			log.Printf("wrote %s", *outputName)
+		}
+	}
+}
+
+// inputState describes the files of the input called name, by name, size
+// and modification time, so that it changes whenever they do.
+func inputState(name string) (string, error) {
+	files, err := InputFiles(name)
+	if err != nil {
+		return err.Error(), err
+	}
+	sort.Strings(files)
+	var state bytes.Buffer
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err.Error(), err
+		}
+		fmt.Fprintf(&state, "%s %d %d\n", file, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return state.String(), nil
+}
@@ -0,0 +1,204 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// har is the part of an HTTP Archive (HAR) file that ParseHar reads.
+type har struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string `json:"method"`
+		URL      string `json:"url"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// ParseHar parses an HTTP Archive (HAR), as exported by browsers, into
+// types for the request and response bodies of each endpoint. Endpoints
+// are told apart by method and by the path of their URL, with segments
+// that look like identifiers, such as numbers and UUIDs, standing for any
+// value. Their types are named after them, e.g. GetUsersByIDResponse for
+// GET /users/{id}. Bodies of error responses (status 400 and above) make
+// up a type of their own, e.g. GetUsersByIDError. Bodies are parsed
+// according to their MIME type, and those that are neither JSON nor YAML
+// are ignored. ParseHar returns a map[string]interface{} of the bodies of
+// each type, as a []interface{}, by the name of the type.
+func ParseHar(input io.Reader) (interface{}, error) {
+	v, err := parseHar(input)
+	if err != nil {
+		return nil, err
+	}
+	bodies := make(map[string]interface{})
+	for _, doc := range v.(samples) {
+		n := doc.(named)
+		vs, _ := bodies[n.name].([]interface{})
+		bodies[n.name] = append(vs, plain(n.value))
+	}
+	return bodies, nil
+}
+
+// parseHar parses an HTTP Archive into the named samples of the types of
+// its bodies.
+func parseHar(input io.Reader) (interface{}, error) {
+	var h har
+	p := newPositionReader(input)
+	if err := json.NewDecoder(p).Decode(&h); err != nil {
+		return nil, p.jsonError(err)
+	}
+
+	var docs samples
+	for i, e := range h.Log.Entries {
+		if d := e.Request.PostData; d != nil {
+			vs, err := endpointSamples(e.Request.Method, e.Request.URL, 0, d.MimeType, []byte(d.Text))
+			if err != nil {
+				return nil, bodyError(fmt.Sprintf("$.log.entries[%d].request", i), err)
+			}
+			docs = append(docs, vs...)
+		}
+
+		c := e.Response.Content
+		text := []byte(c.Text)
+		if c.Encoding == "base64" {
+			var err error
+			if text, err = base64.StdEncoding.DecodeString(c.Text); err != nil {
+				return nil, &PathError{Path: fmt.Sprintf("$.log.entries[%d].response.content", i), Reason: "invalid base64 text"}
+			}
+		}
+		vs, err := endpointSamples(e.Request.Method, e.Request.URL, e.Response.Status, c.MimeType, text)
+		if err != nil {
+			return nil, bodyError(fmt.Sprintf("$.log.entries[%d].response", i), err)
+		}
+		docs = append(docs, vs...)
+	}
+	return docs, nil
+}
+
+// endpointSamples parses the body of a request of method to rawurl, or of
+// its response if status is not 0, into named samples of the type of the
+// endpoint's requests, responses or error responses. Bodies of a MIME
+// type that is neither JSON nor YAML, and empty ones, are not sampled.
+func endpointSamples(method, rawurl string, status int, mimeType string, body []byte) (samples, error) {
+	parser := parserFor(mimeType)
+	if parser == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	name, endpoint, err := harEndpoint(method, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case status == 0:
+		name, endpoint = name+"Request", "the requests of "+endpoint
+	case status >= 400:
+		name, endpoint = name+"Error", "the error responses of "+endpoint
+	default:
+		name, endpoint = name+"Response", "the responses of "+endpoint
+	}
+	vs, ok := v.(samples)
+	if !ok {
+		vs = samples{v}
+	}
+	docs := make(samples, len(vs))
+	for i, v := range vs {
+		docs[i] = named{name: name, origin: endpoint, value: v}
+	}
+	return docs, nil
+}
+
+// bodyError reports an invalid entry at path p of a HAR file.
+func bodyError(p string, err error) error {
+	if e, ok := err.(*SyntaxError); ok {
+		e.File = "body"
+		return &PathError{Path: p, Reason: "invalid " + e.Error()}
+	}
+	return &PathError{Path: p, Reason: err.Error()}
+}
+
+// harEndpoint returns the name of the endpoint a request of method to
+// rawurl is made to, and its description, e.g. "GET /users/{id}".
+func harEndpoint(method, rawurl string) (name, endpoint string, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", err
+	}
+
+	name = FmtFieldName(strings.ToLower(method))
+	var template []string
+	for _, seg := range strings.Split(u.Path, "/") {
+		// e.g. the extension of /items/5.json
+		var ext string
+		if i := strings.LastIndex(seg, "."); i > 0 {
+			seg, ext = seg[:i], seg[i:]
+		}
+		switch {
+		case seg == "":
+			continue
+		case isPathParam(seg):
+			name += "ByID"
+			template = append(template, "{id}"+ext)
+		default:
+			name += FmtFieldName(seg)
+			template = append(template, seg+ext)
+		}
+	}
+	if len(template) == 0 {
+		name += "Root"
+	}
+	return name, strings.ToUpper(method) + " /" + strings.Join(template, "/"), nil
+}
+
+// isPathParam reports whether a segment of a URL path looks like an
+// identifier rather than a fixed part of the path: a number, a UUID, or a
+// long hexadecimal or alphanumeric string containing digits.
+func isPathParam(seg string) bool {
+	var digits, other int
+	for _, r := range seg {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case strings.ContainsRune("abcdefABCDEF-", r):
+		case unicode.IsLetter(r) || r == '_':
+			other++
+		default:
+			return false
+		}
+	}
+	switch {
+	case digits == len(seg):
+		return true
+	case digits == 0:
+		return false
+	case other == 0:
+		return len(seg) >= 8
+	}
+	return len(seg) >= 16
+}
@@ -0,0 +1,111 @@
+package gojson
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// ParseHttp parses a raw HTTP response, such as the output of curl -i: a
+// status line and headers, followed by the body. The body is parsed with
+// ParseJson or ParseYaml according to its Content-Type, or as JSON if it
+// has none. Interim and redirect responses printed before the final one,
+// as by curl -L, are skipped.
+func ParseHttp(input io.Reader) (interface{}, error) {
+	v, err := parseHttp(input)
+	return plain(v), err
+}
+
+// parseHttp parses a raw HTTP response as ParseHttp does, keeping what
+// plain leaves out.
+func parseHttp(input io.Reader) (interface{}, error) {
+	b, err := readFile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	line := 0 // lines before b
+	for {
+		status, header, n, err := readHttpHeader(b)
+		if err != nil {
+			if e, ok := err.(*SyntaxError); ok && e.Line > 0 {
+				e.Line += line
+			}
+			return nil, err
+		}
+		line += bytes.Count(b[:n], []byte("\n"))
+		body := b[n:]
+
+		// skip to the next response, if another one follows
+		next := body
+		if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length <= len(body) {
+			next = body[length:]
+		}
+		if trimmed := bytes.TrimLeft(next, "\r\n"); status/100 == 1 || bytes.HasPrefix(trimmed, []byte("HTTP/")) {
+			line += bytes.Count(b[n:len(b)-len(trimmed)], []byte("\n"))
+			b = trimmed
+			continue
+		}
+
+		contentType := header.Get("Content-Type")
+		parser := parserFor(contentType)
+		if contentType == "" {
+			parser = ParseJson
+		}
+		if parser == nil {
+			return nil, &SyntaxError{Reason: "unsupported Content-Type " + strconv.Quote(contentType)}
+		}
+		v, err := parser(bytes.NewReader(body))
+		if e, ok := err.(*SyntaxError); ok && e.Line > 0 {
+			e.Line += line
+		}
+		return v, err
+	}
+}
+
+// readHttpHeader reads the status line and headers at the start of b,
+// returning the status code and the length of the header.
+func readHttpHeader(b []byte) (status int, header textproto.MIMEHeader, n int, err error) {
+	br := bytes.NewReader(b)
+	r := bufio.NewReader(br)
+	tr := textproto.NewReader(r)
+
+	statusLine, err := tr.ReadLine()
+	if err != nil {
+		return 0, nil, 0, &SyntaxError{Reason: "missing status line"}
+	}
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return 0, nil, 0, &SyntaxError{Line: 1, Column: 1, Reason: "malformed status line " + strconv.Quote(statusLine)}
+	}
+	if status, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, nil, 0, &SyntaxError{Line: 1, Column: len(fields[0]) + 2, Reason: "malformed status code " + strconv.Quote(fields[1])}
+	}
+
+	header, err = tr.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return 0, nil, 0, &SyntaxError{Reason: "malformed header: " + err.Error()}
+	}
+	return status, header, len(b) - br.Len() - r.Buffered(), nil
+}
+
+// parserFor returns the parser of a body of the given Content-Type, or nil
+// if there is none. Its values are those Infer samples, see plain.
+func parserFor(contentType string) Parser {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		// application/json, application/problem+json, text/json...
+		return ParseJson
+	case strings.HasSuffix(mediaType, "yaml"):
+		return parseYaml
+	}
+	return nil
+}
@@ -100,18 +100,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"go/format"
 	"io"
-	"math"
-	"reflect"
-	"sort"
 	"strconv"
 	"strings"
 	"unicode"
 
-	"gopkg.in/yaml.v2"
+	"github.com/BurntSushi/toml"
 )
 
+// ForceFloats makes every number float64, as FloatNumbers does.
+//
+// Deprecated: set Options.Numbers to FloatNumbers, which unlike a global
+// is safe to vary between concurrent calls.
 var ForceFloats bool
 
 // commonInitialisms is a set of common initialisms.
@@ -170,20 +170,91 @@ type Parser func(io.Reader) (interface{}, error)
 
 func ParseJson(input io.Reader) (interface{}, error) {
 	var result interface{}
-	if err := json.NewDecoder(input).Decode(&result); err != nil {
-		return nil, err
+	p := newPositionReader(input)
+	if err := json.NewDecoder(p).Decode(&result); err != nil {
+		return nil, p.jsonError(err)
 	}
 	return result, nil
 }
 
+// ParseYaml parses a stream of YAML documents into the document, or into
+// a []interface{} of the documents if there are several. Mappings whose
+// keys are not all strings are decoded as map[interface{}]interface{}.
+// Infer merges each document of a stream as a sample.
 func ParseYaml(input io.Reader) (interface{}, error) {
-	var result interface{}
-	b, err := readFile(input)
-	if err != nil {
+	v, err := parseYaml(input)
+	return plain(v), err
+}
+
+// parseYaml parses a stream of YAML documents into samples if there are
+// several, keeping mappings with keys other than strings as keyedMaps.
+func parseYaml(input io.Reader) (interface{}, error) {
+	var docs samples
+	if err := readYaml(input, func(doc interface{}) { docs = append(docs, doc) }); err != nil {
 		return nil, err
 	}
-	if err := yaml.Unmarshal(b, &result); err != nil {
-		return nil, err
+	switch len(docs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// samples are the values of a stream, each a sample of the same type
+// unless it is named.
+type samples []interface{}
+
+// A named sample is sampled into a type of its own rather than the one
+// Options describe, such as the body of a response in a HAR file.
+type named struct {
+	name   string // name of the type
+	origin string // what the samples of the type are, for doc comments
+	value  interface{}
+}
+
+// plain returns v without what parsers keep for Infer alone: samples
+// become a []interface{}, keyedMaps a map[interface{}]interface{}, and
+// documented values lose their comments.
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case samples:
+		docs := make([]interface{}, len(v))
+		for i, doc := range v {
+			docs[i] = plain(doc)
+		}
+		return docs
+	case documented:
+		return plain(v.value)
+	case keyedMap:
+		m := make(map[interface{}]interface{}, len(v.values))
+		for key, value := range v.values {
+			k, ok := v.keys[key]
+			if !ok {
+				k = key
+			}
+			m[k] = plain(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = plain(value)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = plain(e)
+		}
+	}
+	return v
+}
+
+// ParseToml parses a TOML document. Integers and floats keep their TOML
+// types, and datetimes are decoded as time.Time.
+func ParseToml(input io.Reader) (interface{}, error) {
+	var result map[string]interface{}
+	if _, err := toml.NewDecoder(input).Decode(&result); err != nil {
+		return nil, tomlError(err)
 	}
 	return result, nil
 }
@@ -192,65 +263,99 @@ func readFile(input io.Reader) ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
 	_, err := io.Copy(buf, input)
 	if err != nil {
-		return []byte{}, nil
+		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// Generate a struct definition given a JSON string representation of an object and a name structName.
-func Generate(input io.Reader, parser Parser, structName, pkgName string, tags []string, subStruct bool, convertFloats bool) ([]byte, error) {
-	var subStructMap map[string]string = nil
-	if subStruct {
-		subStructMap = make(map[string]string)
-	}
+// Options configures how Go types are inferred from a document.
+type Options struct {
+	Name          string       // name of the top-level type
+	Package       string       // package clause of the generated source
+	Tags          []string     // struct tags to emit for each field, e.g. "json"
+	SubStruct     bool         // declare nested structs as named types
+	ConvertFloats bool         // infer the types of numbers, see Numbers
+	Numbers       NumberPolicy // how ConvertFloats types numbers
+
+	// ForceFloats makes every number float64.
+	//
+	// Deprecated: set Numbers to FloatNumbers.
+	ForceFloats bool
+
+	// Comments adds doc comments with sample values and statistics
+	// to every field, and the origin of every named type.
+	Comments bool
+	// Source names the input in doc comments, e.g. its file name.
+	Source string
+	// Limits bound the work done inferring types from large inputs.
+	Limits Limits
+
+	// Path is a JSONPath expression, e.g. "$.data.items[*]", selecting
+	// the values the type is generated for, rather than the whole input.
+	// Every value it matches is merged as a sample.
+	Path string
+	// Selections generates a type for each of several JSONPath
+	// expressions. If set, it replaces Name and Path.
+	Selections []Selection
+
+	// GroupBy names discriminator keys, such as "kind" and "apiVersion",
+	// that tell apart different kinds of documents in a stream. A type is
+	// generated for each combination of their values, and named after it.
+	// Documents without any of the keys make up the type called Name.
+	GroupBy []string
+
+	// Bases extracts the fields several structs have in common into
+	// embedded base structs.
+	Bases Bases
+
+	// Placeholder chooses the types of the arrays and objects that were
+	// empty in every sample.
+	Placeholder Placeholder
+
+	// GoVersion is the version of Go the generated source targets, e.g.
+	// "1.18". From Go 1.18 on, top-level types that differ only in the
+	// type of one field, such as the envelopes of API responses, are
+	// declared as instantiations of one generic struct.
+	GoVersion string
+}
 
-	var result map[string]interface{}
+// A Selection names the type generated for the values matching a JSONPath
+// expression.
+type Selection struct {
+	Name string
+	Path string
+}
 
-	iresult, err := parser(input)
+// Infer parses input and infers a Model of the Go types that describe the
+// value parser returns. InferFormat samples more than plain values for
+// some formats.
+func Infer(input io.Reader, parser Parser, opts Options) (*Model, error) {
+	s, err := NewSampler(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	switch iresult := iresult.(type) {
-	case map[interface{}]interface{}:
-		result = convertKeysToStrings(iresult)
-	case map[string]interface{}:
-		result = iresult
-	case []interface{}:
-		src := fmt.Sprintf("package %s\n\ntype %s %s\n",
-			pkgName,
-			structName,
-			typeForValue(iresult, structName, tags, subStructMap, convertFloats))
-		formatted, err := format.Source([]byte(src))
-		if err != nil {
-			err = fmt.Errorf("error formatting: %s, was formatting\n%s", err, src)
-		}
-		return formatted, err
-	default:
-		return nil, fmt.Errorf("unexpected type: %T", iresult)
-	}
-
-	src := fmt.Sprintf("package %s\ntype %s %s}",
-		pkgName,
-		structName,
-		generateTypes(result, structName, tags, 0, subStructMap, convertFloats))
-
-	keys := make([]string, 0, len(subStructMap))
-	for key := range subStructMap {
-		keys = append(keys, key)
-	}
-
-	sort.Strings(keys)
-
-	for _, k := range keys {
-		src = fmt.Sprintf("%v\n\ntype %v %v", src, subStructMap[k], k)
+	iresult, err := parser(input)
+	if err != nil {
+		return nil, withFile(err, opts.Source)
 	}
+	s.sample(iresult)
+	return s.Model()
+}
 
-	formatted, err := format.Source([]byte(src))
+// Generate a struct definition given a JSON string representation of an object and a name structName.
+func Generate(input io.Reader, parser Parser, structName, pkgName string, tags []string, subStruct bool, convertFloats bool) ([]byte, error) {
+	m, err := Infer(input, parser, Options{
+		Name:          structName,
+		Package:       pkgName,
+		Tags:          tags,
+		SubStruct:     subStruct,
+		ConvertFloats: convertFloats,
+	})
 	if err != nil {
-		err = fmt.Errorf("error formatting: %s, was formatting\n%s", err, src)
+		return nil, err
 	}
-	return formatted, err
+	return m.Source()
 }
 
 func convertKeysToStrings(obj map[interface{}]interface{}) map[string]interface{} {
@@ -263,99 +368,12 @@ func convertKeysToStrings(obj map[interface{}]interface{}) map[string]interface{
 	return res
 }
 
-// Generate go struct entries for a map[string]interface{} structure
-func generateTypes(obj map[string]interface{}, structName string, tags []string, depth int, subStructMap map[string]string, convertFloats bool) string {
-	structure := "struct {"
-
-	keys := make([]string, 0, len(obj))
-	for key := range obj {
-		keys = append(keys, key)
-	}
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		value := obj[key]
-		valueType := typeForValue(value, structName, tags, subStructMap, convertFloats)
-
-		//value = mergeElements(value)
-
-		//If a nested value, recurse
-		switch value := value.(type) {
-		case []interface{}:
-			if len(value) > 0 {
-				sub := ""
-				if v, ok := value[0].(map[interface{}]interface{}); ok {
-					sub = generateTypes(convertKeysToStrings(v), structName, tags, depth+1, subStructMap, convertFloats) + "}"
-				} else if v, ok := value[0].(map[string]interface{}); ok {
-					sub = generateTypes(v, structName, tags, depth+1, subStructMap, convertFloats) + "}"
-				}
-
-				if sub != "" {
-					subName := sub
-
-					if subStructMap != nil {
-						if val, ok := subStructMap[sub]; ok {
-							subName = val
-						} else {
-							subName = fmt.Sprintf("%v_sub%v", structName, len(subStructMap)+1)
-
-							subStructMap[sub] = subName
-						}
-					}
-
-					valueType = "[]" + subName
-				}
-			}
-		case map[interface{}]interface{}:
-			sub := generateTypes(convertKeysToStrings(value), structName, tags, depth+1, subStructMap, convertFloats) + "}"
-			subName := sub
-
-			if subStructMap != nil {
-				if val, ok := subStructMap[sub]; ok {
-					subName = val
-				} else {
-					subName = fmt.Sprintf("%v_sub%v", structName, len(subStructMap)+1)
-
-					subStructMap[sub] = subName
-				}
-			}
-			valueType = subName
-		case map[string]interface{}:
-			sub := generateTypes(value, structName, tags, depth+1, subStructMap, convertFloats) + "}"
-			subName := sub
-
-			if subStructMap != nil {
-				if val, ok := subStructMap[sub]; ok {
-					subName = val
-				} else {
-					subName = fmt.Sprintf("%v_sub%v", structName, len(subStructMap)+1)
-
-					subStructMap[sub] = subName
-				}
-			}
-
-			valueType = subName
-		}
-
-		fieldName := FmtFieldName(key)
-
-		tagList := make([]string, 0)
-		for _, t := range tags {
-			tagList = append(tagList, fmt.Sprintf("%s:\"%s\"", t, key))
-		}
-
-		structure += fmt.Sprintf("\n%s %s `%s`",
-			fieldName,
-			valueType,
-			strings.Join(tagList, " "))
-	}
-	return structure
-}
-
 // FmtFieldName formats a string as a struct key
 //
 // Example:
-// 	FmtFieldName("foo_id")
+//
+//	FmtFieldName("foo_id")
+//
 // Output: FooID
 func FmtFieldName(s string) string {
 	runes := []rune(s)
@@ -469,44 +487,6 @@ func lintFieldName(name string) string {
 	return string(runes)
 }
 
-// generate an appropriate struct type entry
-func typeForValue(value interface{}, structName string, tags []string, subStructMap map[string]string, convertFloats bool) string {
-	//Check if this is an array
-	if objects, ok := value.([]interface{}); ok {
-		types := make(map[reflect.Type]bool, 0)
-		for _, o := range objects {
-			types[reflect.TypeOf(o)] = true
-		}
-		if len(types) == 1 {
-			return "[]" + typeForValue(mergeElements(objects).([]interface{})[0], structName, tags, subStructMap, convertFloats)
-		}
-		return "[]interface{}"
-	} else if object, ok := value.(map[interface{}]interface{}); ok {
-		return generateTypes(convertKeysToStrings(object), structName, tags, 0, subStructMap, convertFloats) + "}"
-	} else if object, ok := value.(map[string]interface{}); ok {
-		return generateTypes(object, structName, tags, 0, subStructMap, convertFloats) + "}"
-	} else if reflect.TypeOf(value) == nil {
-		return "interface{}"
-	}
-	v := reflect.TypeOf(value).Name()
-	if v == "float64" && convertFloats {
-		v = disambiguateFloatInt(value)
-	}
-	return v
-}
-
-// All numbers will initially be read as float64
-// If the number appears to be an integer value, use int instead
-func disambiguateFloatInt(value interface{}) string {
-	const epsilon = .0001
-	vfloat := value.(float64)
-	if !ForceFloats && math.Abs(vfloat-math.Floor(vfloat+epsilon)) < epsilon {
-		var tmp int64
-		return reflect.TypeOf(tmp).Name()
-	}
-	return reflect.TypeOf(value).Name()
-}
-
 // convert first character ints to strings
 func stringifyFirstChar(str string) string {
 	first := str[:1]
@@ -519,66 +499,3 @@ func stringifyFirstChar(str string) string {
 
 	return intToWordMap[i] + "_" + str[1:]
 }
-
-func mergeElements(i interface{}) interface{} {
-	switch i := i.(type) {
-	default:
-		return i
-	case []interface{}:
-		l := len(i)
-		if l == 0 {
-			return i
-		}
-		for j := 1; j < l; j++ {
-			i[0] = mergeObjects(i[0], i[j])
-		}
-		return i[0:1]
-	}
-}
-
-func mergeObjects(o1, o2 interface{}) interface{} {
-	if o1 == nil {
-		return o2
-	}
-
-	if o2 == nil {
-		return o1
-	}
-
-	if reflect.TypeOf(o1) != reflect.TypeOf(o2) {
-		return nil
-	}
-
-	switch i := o1.(type) {
-	default:
-		return o1
-	case []interface{}:
-		if i2, ok := o2.([]interface{}); ok {
-			i3 := append(i, i2...)
-			return mergeElements(i3)
-		}
-		return mergeElements(i)
-	case map[string]interface{}:
-		if i2, ok := o2.(map[string]interface{}); ok {
-			for k, v := range i2 {
-				if v2, ok := i[k]; ok {
-					i[k] = mergeObjects(v2, v)
-				} else {
-					i[k] = v
-				}
-			}
-		}
-		return i
-	case map[interface{}]interface{}:
-		if i2, ok := o2.(map[interface{}]interface{}); ok {
-			for k, v := range i2 {
-				if v2, ok := i[k]; ok {
-					i[k] = mergeObjects(v2, v)
-				} else {
-					i[k] = v
-				}
-			}
-		}
-		return i
-	}
-}
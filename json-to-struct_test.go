@@ -1,9 +1,12 @@
 package gojson
 
 import (
+	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -41,31 +44,6 @@ func TestInvalidFieldChars(t *testing.T) {
 	}
 }
 
-// TestDisambiguateFloatInt tests that disambiguateFloatInt correctly
-// converts JSON numbers to the desired types.
-func TestDisambiguateFloatInt(t *testing.T) {
-	examples := []struct {
-		FloatsOnly bool
-		In         interface{}
-		Out        string
-	}{
-		{FloatsOnly: false, In: 2.2, Out: "float64"},
-		{FloatsOnly: false, In: 2.0, Out: "int64"},
-		{FloatsOnly: false, In: float64(2), Out: "int64"},
-		{FloatsOnly: true, In: 2.2, Out: "float64"},
-		{FloatsOnly: true, In: 2.0, Out: "float64"},
-		{FloatsOnly: true, In: float64(2), Out: "float64"},
-	}
-
-	for i, ex := range examples {
-		ForceFloats = ex.FloatsOnly
-		if actual := disambiguateFloatInt(ex.In); actual != ex.Out {
-			t.Errorf("[Example %d] got %q, but expected %q", i+1, actual, ex.Out)
-		}
-	}
-	ForceFloats = false
-}
-
 // TestInferFloatInt tests that we can correctly infer a float or an int from a
 // JSON number when no command-line flag is provided.
 func TestInferFloatInt(t *testing.T) {
@@ -114,6 +92,236 @@ func TestYamlNumbers(t *testing.T) {
 	}
 }
 
+// TestToml tests that TOML integers, floats and datetimes keep their types
+func TestToml(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "config.toml"))
+	if err != nil {
+		t.Fatalf("error opening examples/config.toml: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_config.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_config.go.out: %s", err)
+	}
+
+	actual, err := Generate(f, ParseToml, "Config", "gojson", []string{"toml"}, false, false)
+	if err != nil {
+		t.Error(err)
+	}
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
+
+// generateFormat generates the source of the types that describe input in
+// the format called format, as InferFormat infers them.
+func generateFormat(t *testing.T, input io.Reader, format string, opts Options) []byte {
+	f, ok := LookupFormat(format)
+	if !ok {
+		t.Fatalf("unknown format %s", format)
+	}
+	m, err := InferFormat(input, f, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := m.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+// TestYamlTypes tests that YAML merge keys, non-string keys, timestamps and binary values keep their types
+func TestYamlTypes(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "database.yaml"))
+	if err != nil {
+		t.Fatalf("error opening examples/database.yaml: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_database.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_database.go.out: %s", err)
+	}
+
+	actual := generateFormat(t, f, "yaml", Options{Name: "Database", Package: "gojson", Tags: []string{"yaml"}, SubStruct: true})
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
+
+// TestXml tests that attributes, text, repeated elements and namespaces get xml tags
+func TestXml(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "feed.xml"))
+	if err != nil {
+		t.Fatalf("error opening examples/feed.xml: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_feed.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_feed.go.out: %s", err)
+	}
+
+	actual, err := Generate(f, ParseXml, "Feed", "gojson", []string{"xml"}, false, false)
+	if err != nil {
+		t.Error(err)
+	}
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
+
+// TestHar tests that a HAR file generates request and response types per endpoint
+func TestHar(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "session.har"))
+	if err != nil {
+		t.Fatalf("error opening examples/session.har: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_session.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_session.go.out: %s", err)
+	}
+
+	actual := generateFormat(t, f, "har", Options{Name: "Session", Package: "gojson", Tags: []string{"json"}, ConvertFloats: true})
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
+
+// TestHttp tests that raw HTTP responses are parsed according to their Content-Type
+func TestHttp(t *testing.T) {
+	examples := []struct {
+		In   string
+		Type string
+	}{
+		{In: "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"a\": 1}", Type: "int64"},
+		{In: "HTTP/2 200\ncontent-type: application/yaml\n\na: 1\n", Type: "int"},
+		{In: "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 302 Found\r\nContent-Length: 3\r\n\r\nabcHTTP/1.1 200 OK\r\n\r\n{\"a\": 2}", Type: "int64"},
+	}
+	for i, ex := range examples {
+		expected := "package gojson\n\ntype Response struct {\n\tA " + ex.Type + " `json:\"a\"`\n}\n"
+		actual, err := Generate(strings.NewReader(ex.In), ParseHttp, "Response", "gojson", []string{"json"}, false, true)
+		if err != nil {
+			t.Errorf("[Example %d] %s", i+1, err)
+		} else if string(actual) != expected {
+			t.Errorf("[Example %d] '%s' (expected) != '%s' (actual)", i+1, expected, actual)
+		}
+	}
+
+	_, err := Infer(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\n\"a\": x}"), ParseHttp, Options{Name: "Response"})
+	if e, ok := err.(*SyntaxError); !ok || e.Line != 5 {
+		t.Errorf("expected a syntax error on line 5, got %v", err)
+	}
+	_, err = Infer(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<p>"), ParseHttp, Options{Name: "Response"})
+	if err == nil {
+		t.Error("expected an error for an HTML body")
+	}
+}
+
+// TestPlainValues tests that the exported parsers return plain Go values
+func TestPlainValues(t *testing.T) {
+	examples := []struct {
+		Parser   Parser
+		In       string
+		Expected interface{}
+	}{
+		{Parser: ParseYaml, In: "a: 1\n---\na: 2\n", Expected: []interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}}},
+		{Parser: ParseYaml, In: "1: a\n2: b\n", Expected: map[interface{}]interface{}{1: "a", 2: "b"}},
+		{Parser: ParseJson5, In: "{\n// the a\na: [1],\n}", Expected: map[string]interface{}{"a": []interface{}{1.0}}},
+	}
+	for i, ex := range examples {
+		v, err := ex.Parser(strings.NewReader(ex.In))
+		if err != nil {
+			t.Errorf("[Example %d] %s", i+1, err)
+		} else if !reflect.DeepEqual(v, ex.Expected) {
+			t.Errorf("[Example %d] %#v (expected) != %#v (actual)", i+1, ex.Expected, v)
+		}
+	}
+
+	f, err := os.Open(filepath.Join("examples", "session.har"))
+	if err != nil {
+		t.Fatalf("error opening examples/session.har: %s", err)
+	}
+	defer f.Close()
+	v, err := ParseHar(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodies, ok := v.(map[string]interface{})
+	if !ok || len(bodies) == 0 {
+		t.Fatalf("expected the bodies of each type, got %#v", v)
+	}
+	for name, vs := range bodies {
+		if b, err := json.Marshal(vs); err != nil || string(b) == "{}" || string(b) == "[{}]" {
+			t.Errorf("%s: bodies marshal to %s, %v", name, b, err)
+		}
+	}
+}
+
+// TestInferFormat tests that InferFormat samples the values of a format beyond plain ones, whatever its Parser
+func TestInferFormat(t *testing.T) {
+	const in = "names:\n  1: a\n  2: b\n"
+	opts := Options{Name: "Names", Package: "gojson", Tags: []string{"yaml"}}
+
+	f, _ := LookupFormat("yaml")
+	wrapped := f
+	wrapped.Parser = func(input io.Reader) (interface{}, error) {
+		return ParseYaml(input)
+	}
+	for _, f := range []Format{f, wrapped} {
+		m, err := InferFormat(strings.NewReader(in), f, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ := m.Types[0].Type.Struct.Fields[0].Type.String(); typ != "map[int]string" {
+			t.Errorf("expected map[int]string, got %s", typ)
+		}
+	}
+}
+
+// TestJson5 tests that JSON5 is accepted and comments on keys document their fields
+func TestJson5(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "settings.json5"))
+	if err != nil {
+		t.Fatalf("error opening examples/settings.json5: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_settings.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_settings.go.out: %s", err)
+	}
+
+	actual := generateFormat(t, f, "json5", Options{Name: "Settings", Package: "gojson", Tags: []string{"json"}, ConvertFloats: true})
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+
+	for _, in := range []string{`{a: 1,,}`, `{a: 'b}`, `{a: +-1}`, `{a: 1} 2`, `{/* a: 1}`, `{a: 00}`, `{a: -01.5}`} {
+		if _, err := ParseJson5(strings.NewReader(in)); err == nil {
+			t.Errorf("expected an error parsing %q", in)
+		}
+	}
+
+	// numbers with leading zeros are syntax errors
+	_, err = ParseJson5(strings.NewReader("{\n  a: 007,\n}"))
+	if e, ok := err.(*SyntaxError); !ok || e.Line != 2 || e.Column != 6 {
+		t.Errorf("expected a syntax error at 2:6 for a leading zero, got %v", err)
+	}
+	if _, err := ParseJson5(strings.NewReader(`{a: 0, b: 0.5, c: 0x1F, d: -0e1, e: .5}`)); err != nil {
+		t.Errorf("expected numbers starting with 0 to parse, got %s", err)
+	}
+}
+
 // Test example document
 func TestExample(t *testing.T) {
 	i, err := os.Open(filepath.Join("examples", "example.json"))
@@ -159,3 +367,61 @@ func TestFmtFieldName(t *testing.T) {
 		}
 	}
 }
+
+// TestComments tests that doc comments describe the observed samples
+func TestComments(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "comments.json"))
+	if err != nil {
+		t.Fatalf("error opening examples/comments.json: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_comments.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_comments.go.out: %s", err)
+	}
+
+	m, err := Infer(f, ParseJson, Options{
+		Name:          "Repository",
+		Package:       "gojson",
+		Tags:          []string{"json"},
+		SubStruct:     true,
+		ConvertFloats: true,
+		Comments:      true,
+		Source:        "examples/comments.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := m.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
+
+// TestNestedArrays tests that the elements of arrays of arrays, at any depth, are merged into one type
+func TestNestedArrays(t *testing.T) {
+	f, err := os.Open(filepath.Join("examples", "grid.json"))
+	if err != nil {
+		t.Fatalf("error opening examples/grid.json: %s", err)
+	}
+	defer f.Close()
+
+	expected, err := ioutil.ReadFile(filepath.Join("examples", "expected_grid.go.out"))
+	if err != nil {
+		t.Fatalf("error reading expected_grid.go.out: %s", err)
+	}
+
+	actual, err := Generate(f, ParseJson, "Grid", "gojson", []string{"json"}, true, true)
+	if err != nil {
+		t.Error(err)
+	}
+	sactual, sexpected := string(actual), string(expected)
+	if sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
@@ -0,0 +1,432 @@
+package gojson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// A documented value is the value of a key with comments attached to it.
+type documented struct {
+	doc   string
+	value interface{}
+}
+
+// ParseJson5 parses a JSON5 document, which includes JSON with comments
+// (JSONC). It accepts comments, trailing commas, unquoted keys, single
+// quoted strings and the numbers of JavaScript. Comments on the lines
+// before a key, or on the same line after its value, document the field
+// the key is generated into when Infer parses it; ParseJson5 returns the
+// values alone.
+func ParseJson5(input io.Reader) (interface{}, error) {
+	v, err := parseJson5(input)
+	return plain(v), err
+}
+
+// parseJson5 parses a JSON5 document, keeping the values of keys with
+// comments as documented values.
+func parseJson5(input io.Reader) (interface{}, error) {
+	b, err := readFile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &json5Parser{in: b}
+	for i, c := range b {
+		if c == '\n' {
+			p.newlines = append(p.newlines, i)
+		}
+	}
+	p.space()
+	v, err := p.value()
+	if err == nil {
+		p.space()
+		if p.pos < len(p.in) {
+			err = p.errorf("unexpected %q after top-level value", p.in[p.pos])
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type json5Parser struct {
+	in       []byte
+	pos      int
+	newlines []int    // offsets of the newlines of in
+	comments []string // comments skipped since the last key or value
+	line     int      // line of the end of the last value, 0 if none
+}
+
+// errorf returns a SyntaxError at the current position.
+func (p *json5Parser) errorf(format string, args ...interface{}) error {
+	line := p.lineOf(p.pos)
+	column := p.pos + 1
+	if line > 1 {
+		column = p.pos - p.newlines[line-2]
+	}
+	return &SyntaxError{Line: line, Column: column, Reason: fmt.Sprintf(format, args...)}
+}
+
+// lineOf returns the 1-based line of offset pos.
+func (p *json5Parser) lineOf(pos int) int {
+	return sort.SearchInts(p.newlines, pos) + 1
+}
+
+// space skips white space and comments, collecting the comments.
+func (p *json5Parser) space() {
+	for p.pos < len(p.in) {
+		r, size := utf8.DecodeRune(p.in[p.pos:])
+		switch {
+		case unicode.IsSpace(r) || r == '\ufeff':
+			p.pos += size
+		case bytes.HasPrefix(p.in[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.in[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.in) - p.pos
+			}
+			p.comment(string(p.in[p.pos+2 : p.pos+end]))
+			p.pos += end
+		case bytes.HasPrefix(p.in[p.pos:], []byte("/*")):
+			end := bytes.Index(p.in[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				p.pos = len(p.in)
+				return
+			}
+			p.comment(string(p.in[p.pos+2 : p.pos+2+end]))
+			p.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+// comment collects the text of a comment, without the decoration of block
+// comments.
+func (p *json5Parser) comment(text string) {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if i > 0 {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		}
+		lines[i] = line
+	}
+	text = strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return
+	}
+
+	// a comment on the line a value ended on documents that value
+	if p.line > 0 && p.lineOf(p.pos) == p.line {
+		p.comments = append(p.comments, "\x00"+text)
+		return
+	}
+	p.comments = append(p.comments, text)
+}
+
+// takeComments returns the comments collected since the last key or value
+// that precede the next one, and those that trail the last value.
+func (p *json5Parser) takeComments() (leading, trailing string) {
+	var lead, trail []string
+	for _, c := range p.comments {
+		if strings.HasPrefix(c, "\x00") {
+			trail = append(trail, c[1:])
+		} else {
+			lead = append(lead, c)
+		}
+	}
+	p.comments = nil
+	return strings.Join(lead, "\n"), strings.Join(trail, "\n")
+}
+
+func (p *json5Parser) value() (interface{}, error) {
+	if p.pos >= len(p.in) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	var v interface{}
+	var err error
+	switch c := p.in[p.pos]; {
+	case c == '{':
+		v, err = p.object()
+	case c == '[':
+		v, err = p.array()
+	case c == '"' || c == '\'':
+		v, err = p.string()
+	default:
+		v, err = p.literal()
+	}
+	p.line = p.lineOf(p.pos)
+	return v, err
+}
+
+func (p *json5Parser) object() (interface{}, error) {
+	p.pos++ // {
+	p.comments = nil
+	obj := make(map[string]interface{})
+
+	var last string  // key of the previous value
+	var lastDoc bool // whether it has a leading comment
+	attach := func(trailing string) {
+		if trailing == "" || last == "" || lastDoc {
+			return
+		}
+		obj[last] = documented{doc: trailing, value: obj[last]}
+	}
+
+	for {
+		p.space()
+		leading, trailing := p.takeComments()
+		attach(trailing)
+		if p.pos >= len(p.in) {
+			return nil, p.errorf("unexpected end of input")
+		}
+		if p.in[p.pos] == '}' {
+			p.pos++
+			return obj, nil
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		p.line = 0
+		p.space()
+		if p.pos >= len(p.in) || p.in[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		p.pos++
+		p.space()
+		more, _ := p.takeComments()
+		if more != "" {
+			leading = strings.TrimPrefix(leading+"\n"+more, "\n")
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if leading != "" {
+			v = documented{doc: leading, value: v}
+		}
+		obj[key] = v
+		last, lastDoc = key, leading != ""
+
+		p.space()
+		if p.pos < len(p.in) && p.in[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		p.space()
+		_, trailing = p.takeComments()
+		attach(trailing)
+		if p.pos >= len(p.in) || p.in[p.pos] != '}' {
+			return nil, p.errorf("expected ',' or '}' after object value")
+		}
+		p.pos++
+		return obj, nil
+	}
+}
+
+// key reads a quoted key or an identifier.
+func (p *json5Parser) key() (string, error) {
+	if c := p.in[p.pos]; c == '"' || c == '\'' {
+		return p.string()
+	}
+	start := p.pos
+	for p.pos < len(p.in) {
+		r, size := utf8.DecodeRune(p.in[p.pos:])
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) && p.pos > start || r == '_' || r == '$') {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return "", p.errorf("invalid character %q looking for key", p.in[p.pos])
+	}
+	return string(p.in[start:p.pos]), nil
+}
+
+func (p *json5Parser) array() (interface{}, error) {
+	p.pos++ // [
+	arr := make([]interface{}, 0)
+	for {
+		p.space()
+		p.comments = nil
+		if p.pos >= len(p.in) {
+			return nil, p.errorf("unexpected end of input")
+		}
+		if p.in[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		p.space()
+		if p.pos < len(p.in) && p.in[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos >= len(p.in) || p.in[p.pos] != ']' {
+			return nil, p.errorf("expected ',' or ']' after array element")
+		}
+		p.pos++
+		return arr, nil
+	}
+}
+
+func (p *json5Parser) string() (string, error) {
+	quote := p.in[p.pos]
+	p.pos++
+	var buf bytes.Buffer
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return buf.String(), nil
+		case c == '\n':
+			return "", p.errorf("newline in string")
+		case c == '\\':
+			if err := p.escape(&buf); err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unexpected end of input in string")
+}
+
+// escape reads an escape sequence into buf.
+func (p *json5Parser) escape(buf *bytes.Buffer) error {
+	p.pos++ // backslash
+	if p.pos >= len(p.in) {
+		return p.errorf("unexpected end of input in string")
+	}
+	c := p.in[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		buf.WriteByte('\b')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'v':
+		buf.WriteByte('\v')
+	case '0':
+		buf.WriteByte(0)
+	case '\r':
+		// line continuation
+		if p.pos < len(p.in) && p.in[p.pos] == '\n' {
+			p.pos++
+		}
+	case '\n':
+	case 'x':
+		r, err := p.hex(2)
+		if err != nil {
+			return err
+		}
+		buf.WriteRune(r)
+	case 'u':
+		r, err := p.hex(4)
+		if err != nil {
+			return err
+		}
+		if utf16.IsSurrogate(r) && bytes.HasPrefix(p.in[p.pos:], []byte(`\u`)) {
+			p.pos += 2
+			r2, err := p.hex(4)
+			if err != nil {
+				return err
+			}
+			r = utf16.DecodeRune(r, r2)
+		}
+		buf.WriteRune(r)
+	default:
+		buf.WriteByte(c)
+	}
+	return nil
+}
+
+// hex reads n hexadecimal digits.
+func (p *json5Parser) hex(n int) (rune, error) {
+	if p.pos+n > len(p.in) {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	v, err := strconv.ParseUint(string(p.in[p.pos:p.pos+n]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += n
+	return rune(v), nil
+}
+
+// literal reads a number, true, false or null.
+func (p *json5Parser) literal() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		if !(c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			break
+		}
+		p.pos++
+	}
+	lit := string(p.in[start:p.pos])
+
+	switch lit {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, p.errorf("invalid character %q looking for value", p.in[p.pos])
+	}
+
+	sign, digits := 1.0, lit
+	switch digits[0] {
+	case '-':
+		sign, digits = -1, digits[1:]
+	case '+':
+		digits = digits[1:]
+	}
+	switch {
+	case digits == "Infinity":
+		return sign * math.Inf(1), nil
+	case digits == "NaN":
+		return math.NaN(), nil
+	case strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X"):
+		if n, err := strconv.ParseUint(digits[2:], 16, 64); err == nil {
+			return sign * float64(n), nil
+		}
+	case len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9':
+		// leading zeros, which JavaScript once read as octal, are invalid
+	case digits != "" && (digits[0] == '.' || digits[0] >= '0' && digits[0] <= '9'):
+		if n, err := strconv.ParseFloat(digits, 64); err == nil {
+			return sign * n, nil
+		}
+	}
+	p.pos = start
+	return nil, p.errorf("invalid value %q", lit)
+}
@@ -0,0 +1,169 @@
+package gojson
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Manifest lists the types to generate in one run, such as those of a
+// package. It is read from a YAML file by ReadManifest, e.g.
+//
+//	package: api
+//	tags: [json]
+//	types:
+//	  - name: User
+//	    inputs: [samples/user.json]
+//	    output: user.go
+//	  - name: Repository
+//	    inputs: [samples/repos/]
+//	    path: $.items[*]
+//	    output: repos.go
+type Manifest struct {
+	Package  string   `yaml:"package"`  // package clause of the outputs, "main" by default
+	Tags     []string `yaml:"tags"`     // struct tags, by default those of the format of the first type
+	Comments bool     `yaml:"comments"` // see Options.Comments
+	Bases    float64  `yaml:"bases"`    // see Bases.Threshold
+	Go       string   `yaml:"go"`       // see Options.GoVersion
+	Types    []Target `yaml:"types"`
+
+	// Numbers is "int64", "smallest" or "float64", see NumberPolicy.
+	Numbers NumberPolicy `yaml:"numbers"`
+	// Placeholder is "interface", "raw", "map", "todo" or "named", see
+	// Placeholder.
+	Placeholder Placeholder `yaml:"placeholder"`
+
+	dir string // directory of the manifest, which paths are relative to
+}
+
+// A Target is a type listed in a Manifest.
+type Target struct {
+	Name   string   `yaml:"name"`
+	Inputs []string `yaml:"inputs"` // sample files or directories, see InputFiles
+	Path   string   `yaml:"path"`   // JSONPath expression selecting the values, see Options.Path
+	Fmt    string   `yaml:"fmt"`    // format of the inputs, "json" by default
+	Output string   `yaml:"output"` // Go file the type is written to
+}
+
+// ReadManifest reads the manifest file called name. Paths in it are
+// relative to its directory.
+func ReadManifest(name string) (*Manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Manifest{dir: filepath.Dir(name)}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil {
+		return nil, withFile(yamlError(err), name)
+	}
+
+	if m.Package == "" {
+		m.Package = "main"
+	}
+	names := make(map[string]bool)
+	for i, t := range m.Types {
+		if t.Fmt == "" {
+			m.Types[i].Fmt = "json"
+		}
+		switch _, ok := LookupFormat(m.Types[i].Fmt); {
+		case t.Name == "":
+			return nil, fmt.Errorf("%s: type %d has no name", name, i+1)
+		case names[t.Name]:
+			return nil, fmt.Errorf("%s: type %s is listed twice", name, t.Name)
+		case len(t.Inputs) == 0:
+			return nil, fmt.Errorf("%s: type %s has no inputs", name, t.Name)
+		case t.Output == "":
+			return nil, fmt.Errorf("%s: type %s has no output", name, t.Name)
+		case !ok:
+			return nil, fmt.Errorf("%s: type %s: fmt must be %s", name, t.Name, FormatNames())
+		}
+		names[t.Name] = true
+	}
+	return m, nil
+}
+
+// Generate infers every type of m, in parallel, and returns the source of
+// every output file by path. The types written to the files of the same
+// directory make up a package, generated by one Session: each sub-struct
+// is declared once, in the output file of the first type (by name) that
+// uses it.
+func (m *Manifest) Generate() (map[string][]byte, error) {
+	opts := Options{
+		Package:     m.Package,
+		Tags:        m.Tags,
+		Comments:    m.Comments,
+		Bases:       Bases{Threshold: m.Bases},
+		GoVersion:   m.Go,
+		Numbers:     m.Numbers,
+		Placeholder: m.Placeholder,
+	}
+	if len(opts.Tags) == 0 && len(m.Types) > 0 {
+		f, _ := LookupFormat(m.Types[0].Fmt)
+		opts.Tags = []string{f.Tag}
+	}
+
+	// the session of each package, and the output file of each type
+	var dirs []string
+	sessions := make(map[string]*Session)
+	outputs := make(map[string]string)
+	for _, t := range m.Types {
+		output := filepath.Join(m.dir, t.Output)
+		dir := filepath.Dir(output)
+		if sessions[dir] == nil {
+			dirs = append(dirs, dir)
+			sessions[dir] = NewSession(opts)
+		}
+		outputs[t.Name] = output
+	}
+
+	errs := make([]error, len(m.Types))
+	var wg sync.WaitGroup
+	for i, t := range m.Types {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			f, _ := LookupFormat(t.Fmt)
+			inputs := make([]string, len(t.Inputs))
+			for j, input := range t.Inputs {
+				inputs[j] = filepath.Join(m.dir, input)
+			}
+			errs[i] = sessions[filepath.Dir(outputs[t.Name])].AddFiles(t.Name, t.Path, f, inputs...)
+		}(i, t)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sources := make(map[string][]byte)
+	for _, dir := range dirs {
+		model, err := sessions[dir].Model()
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range model.Types {
+			// types named by their samples, such as HAR endpoints, go
+			// to the output of the type they were inferred for
+			if t, ok := sessions[dir].added[d.Name]; ok {
+				outputs[d.Name] = outputs[t]
+			}
+		}
+		for output, file := range model.Split(outputs) {
+			src, err := file.Source()
+			if err != nil {
+				return nil, err
+			}
+			sources[output] = src
+		}
+	}
+	return sources, nil
+}
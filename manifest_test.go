@@ -0,0 +1,75 @@
+package gojson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestManifest tests that the types of a manifest share their sub-structs and get the imports they use
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"gojson.yaml": `package: api
+types:
+  - name: User
+    inputs: [user.json]
+    output: user.go
+  - name: Repository
+    inputs: [repos]
+    path: $.items[*]
+    output: repos.go
+  - name: Config
+    inputs: [config.yaml]
+    fmt: yaml
+    output: config.go
+`,
+		"user.json":     `{"id": 1, "owner": {"login": "a", "id": 2}}`,
+		"repos/1.json":  `{"items": [{"name": "r", "owner": {"login": "b", "id": 3}}]}`,
+		"repos/2.json":  `{"items": []}`,
+		"config.yaml":   "when: 2001-12-14T21:59:43Z\n",
+		"repos/.hidden": `not json`,
+	}
+	os.Mkdir(filepath.Join(dir, "repos"), 0755)
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := ReadManifest(filepath.Join(dir, "gojson.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources, err := m.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"user.go":   "package api\n\ntype User struct {\n\tID    int64 `json:\"id\"`\n\tOwner Owner `json:\"owner\"`\n}\n",
+		"repos.go":  "package api\n\ntype Repository struct {\n\tName  string `json:\"name\"`\n\tOwner Owner  `json:\"owner\"`\n}\n\ntype Owner struct {\n\tID    int64  `json:\"id\"`\n\tLogin string `json:\"login\"`\n}\n",
+		"config.go": "package api\n\nimport (\n\t\"time\"\n)\n\ntype Config struct {\n\tWhen time.Time `json:\"when\"`\n}\n",
+	}
+	if len(sources) != len(expected) {
+		t.Errorf("got %d outputs, but expected %d", len(sources), len(expected))
+	}
+	for name, src := range expected {
+		if actual := string(sources[filepath.Join(dir, name)]); actual != src {
+			t.Errorf("%s: '%s' (expected) != '%s' (actual)", name, src, actual)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("types:\n  - name: A\n    output: a.go\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(filepath.Join(dir, "bad.yaml")); err == nil {
+		t.Error("expected an error for a type without inputs")
+	}
+}
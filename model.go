@@ -0,0 +1,852 @@
+package gojson
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Model is the set of Go types inferred from an input document.
+//
+// The built-in renderer (Model.Source) and user supplied templates
+// (Model.ExecuteTemplate) both work from the same Model.
+type Model struct {
+	Package string    // package clause of the generated source
+	Types   []*Decl   // top-level types
+	Imports []string  // packages the types refer to, e.g. "time"
+	Tags    []string  // struct tags emitted for each field
+	Structs []*Struct // named structs, top-level structs first
+
+	// Warnings lists the places where Limits cut inference short.
+	Warnings []string
+	// Suggestions lists the base structs Options.Bases found, whether or
+	// not they were extracted, e.g. "Base: ID, CreatedAt in Issue, User".
+	Suggestions []string
+	// Unresolved lists the arrays and objects that were empty in every
+	// sample, and so are typed as placeholders, e.g. "$.tags: always an
+	// empty array". More samples are needed to infer their types.
+	Unresolved []string
+}
+
+// A Decl declares a top-level type, inferred from the whole input or from
+// the values selected by a JSONPath expression.
+type Decl struct {
+	Name string
+	Doc  string
+	Type *Type  // underlying type
+	Path string // JSONPath of the values the type was inferred from
+
+	// Alias declares the type as an alias of Type, an instantiation of a
+	// generic struct, see Options.GoVersion.
+	Alias bool
+}
+
+// A Struct is an inferred struct type.
+type Struct struct {
+	Name   string // empty for structs that are declared inline
+	Doc    string
+	Fields []*Field
+	Params []string // type parameters of a generic struct, e.g. "T"
+
+	// Tuple is set for structs read from and written as arrays, with a
+	// field per element, see tupleFor.
+	Tuple bool
+}
+
+// A Field is a single field of an inferred struct.
+type Field struct {
+	Name     string // Go name of the field
+	Key      string // key of the field in the input document
+	Type     *Type
+	Tag      string // rendered struct tag, without the backquotes
+	Optional bool   // the key was missing from some samples, or was null
+	Doc      string
+	Path     string // JSON path of the field's values, e.g. "$.items[*].id"
+	Embedded bool   // the field embeds a base struct, see Bases
+
+	path   path
+	sample interface{} // a value of the field, for reproducing bugs
+}
+
+// A Type is the Go type inferred for a value. Exactly one of Name, Elem and
+// Struct is set.
+type Type struct {
+	Name   string  // builtin type such as "string" or "interface{}"
+	Key    *Type   // key type of a map, whose value type is Elem
+	Elem   *Type   // element type of a slice or map
+	Struct *Struct // struct type, named or inline
+	Args   []*Type // type arguments of a generic Struct
+
+	// Pointer makes the type a pointer to Struct, for structs that hold
+	// themselves, see findFolds.
+	Pointer bool
+}
+
+// String returns the type as Go source. Named structs are referenced by
+// name, inline structs are spelled out without doc comments.
+func (t *Type) String() string {
+	return t.source(false)
+}
+
+func (t *Type) source(docs bool) string {
+	switch {
+	case t.Key != nil:
+		return "map[" + t.Key.source(docs) + "]" + t.Elem.source(docs)
+	case t.Elem != nil:
+		return "[]" + t.Elem.source(docs)
+	case t.Pointer:
+		u := *t
+		u.Pointer = false
+		return "*" + u.source(docs)
+	case t.Struct != nil:
+		if len(t.Args) > 0 {
+			args := make([]string, len(t.Args))
+			for i, a := range t.Args {
+				args[i] = a.source(docs)
+			}
+			return t.Struct.Name + "[" + strings.Join(args, ", ") + "]"
+		}
+		if t.Struct.Name != "" {
+			return t.Struct.Name
+		}
+		return t.Struct.source(docs)
+	}
+	return t.Name
+}
+
+// source returns the struct type literal, e.g. "struct {\nID int64 `json:\"id\"`}".
+func (s *Struct) source(docs bool) string {
+	structure := "struct {"
+	for _, f := range s.Fields {
+		structure += "\n"
+		if docs {
+			structure += comment(f.Doc)
+		}
+		if !f.Embedded {
+			structure += f.Name + " "
+		}
+		structure += f.Type.source(docs)
+		if f.Tag != "" {
+			structure += " `" + f.Tag + "`"
+		}
+	}
+	return structure + "}"
+}
+
+// key identifies structs of identical shape, regardless of their docs.
+func (s *Struct) key() string {
+	return s.source(false)
+}
+
+// Source renders the model as gofmt'd Go source.
+func (m *Model) Source() ([]byte, error) {
+	if !token.IsIdentifier(m.Package) {
+		return nil, fmt.Errorf("invalid package name %q", m.Package)
+	}
+
+	src := fmt.Sprintf("package %s\n%s%s", m.Package, m.imports(), m.warnings())
+	for _, d := range m.Types {
+		if !token.IsIdentifier(d.Name) {
+			return nil, fmt.Errorf("invalid type name %q", d.Name)
+		}
+		switch {
+		case d.Alias:
+			src = fmt.Sprintf("%v\n\n%stype %v = %v", src, comment(d.Doc), d.Name, d.Type.source(true))
+		case d.Type.Struct == nil:
+			src = fmt.Sprintf("%v\n\n%stype %v %v", src, comment(d.Doc), d.Name, d.Type.source(true))
+		}
+	}
+	for _, s := range m.Structs {
+		name := s.Name
+		if len(s.Params) > 0 {
+			name += "[" + strings.Join(s.Params, ", ") + " any]"
+		}
+		src = fmt.Sprintf("%v\n\n%stype %v %v", src, comment(s.Doc), name, s.source(true))
+		if s.Tuple {
+			src += tupleMethods(s)
+		}
+	}
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return nil, m.internalError(err)
+	}
+	return formatted, nil
+}
+
+// imports returns the import declaration of m.Imports, if any.
+func (m *Model) imports() string {
+	if len(m.Imports) == 0 {
+		return ""
+	}
+	return "\nimport (\n\t\"" + strings.Join(m.Imports, "\"\n\t\"") + "\"\n)\n"
+}
+
+// warnings returns a comment listing m.Warnings, if any.
+func (m *Model) warnings() string {
+	if len(m.Warnings) == 0 {
+		return ""
+	}
+	doc := "Warning: inference was cut short by limits, these types may be incomplete."
+	for _, w := range m.Warnings {
+		doc += "\n  " + w
+	}
+	return "\n" + comment(doc)
+}
+
+// comment formats doc as a line comment, one "//" per line.
+func comment(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	return "// " + strings.Replace(doc, "\n", "\n// ", -1) + "\n"
+}
+
+// kind classifies the values observed at one position of the input.
+type kind int
+
+const (
+	kindNull   kind = iota // nothing but nulls (or nothing at all) observed
+	kindScalar             // strings, numbers and booleans
+	kindObject
+	kindArray
+	kindMap   // maps with keys of a scalar type other than string
+	kindMixed // values of incompatible types were observed
+)
+
+// shape accumulates type information about every value observed at one
+// position in the input, such as a key of an object or the elements of an
+// array.
+type shape struct {
+	kind   kind
+	name   string      // reflect type name of a scalar, e.g. "float64"
+	sample interface{} // first scalar observed
+	fields map[string]*shape
+	elem   *shape
+	seen   int // number of values observed, including nulls
+	nulls  int
+
+	numeric           // numbers observed, whatever their types
+	strings int       // number of strings observed
+	format  string    // format shared by every string, see stringFormat
+	cells   csvColumn // types the CSV cells observed parse as, see csvCell
+
+	skipped int  // array elements not inspected, see Limits
+	dropped int  // values of keys ignored beyond Limits.MaxKeys
+	deep    bool // objects or arrays were not inspected beyond Limits.MaxDepth
+
+	doc string // comment documenting the key of the values in the input
+
+	// tuple detection, for arrays of scalars of the same length
+	positions []*shape // shapes of the elements at each index
+	arrays    int      // arrays whose length was recorded
+	length    int      // length of the first array
+	notTuple  bool     // an array had another length, or a non-scalar element
+}
+
+func (s *shape) null() {
+	s.seen++
+	s.nulls++
+}
+
+func (s *shape) scalar(v interface{}) {
+	s.seen++
+	if !s.is(kindScalar, typeName(v)) {
+		return
+	}
+	if s.sample == nil {
+		s.sample = v
+	}
+	n, isNumber := toFloat(v)
+	str, isString := v.(string)
+	if c, ok := v.(csvCell); ok {
+		// a cell counts as both, until its column is typed, see fieldDoc
+		s.cells.observe(string(c))
+		str, isString = string(c), true
+		f, err := strconv.ParseFloat(str, 64)
+		n, isNumber = f, err == nil
+	}
+	if isNumber {
+		s.number(v, n)
+	}
+	if isString {
+		if f := stringFormat(str); s.strings == 0 {
+			s.format = f
+		} else if f != s.format {
+			s.format = ""
+		}
+		s.strings++
+	}
+}
+
+// typeName returns the name of the Go type of v, a scalar or map key.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "interface{}"
+	case []byte:
+		return "[]byte"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+func (s *shape) object() {
+	s.seen++
+	if s.is(kindObject, "") && s.fields == nil {
+		s.fields = make(map[string]*shape)
+	}
+}
+
+// array records an array and returns the shape its elements merge into.
+func (s *shape) array() *shape {
+	s.seen++
+	if !s.is(kindArray, "") {
+		return new(shape)
+	}
+	if s.elem == nil {
+		s.elem = new(shape)
+	}
+	return s.elem
+}
+
+// mapOf records a map with keys of the type named key, and returns the
+// shape its values merge into.
+func (s *shape) mapOf(key string) *shape {
+	s.seen++
+	if !s.is(kindMap, key) {
+		return new(shape)
+	}
+	if s.elem == nil {
+		s.elem = new(shape)
+	}
+	return s.elem
+}
+
+// field returns the shape of key within an object shape, or nil if the
+// object already has max keys (if max > 0) and key is not one of them.
+func (s *shape) field(key string, max int) *shape {
+	if s.kind != kindObject {
+		return new(shape)
+	}
+	f, ok := s.fields[key]
+	if !ok {
+		if max > 0 && len(s.fields) >= max {
+			s.dropped++
+			return nil
+		}
+		f = new(shape)
+		s.fields[key] = f
+	}
+	return f
+}
+
+// truncate records an object or array that is not inspected.
+func (s *shape) truncate() {
+	s.seen++
+	s.deep = true
+}
+
+// is reports whether values of kind k (and scalar type name) are
+// compatible with the shape, degrading it to kindMixed if they are not.
+func (s *shape) is(k kind, name string) bool {
+	switch {
+	case s.kind == kindNull:
+		s.kind, s.name = k, name
+		return true
+	case s.kind == k && s.name == name:
+		return true
+	case s.kind == kindScalar && k == kindScalar && widerNumber(s.name, name) != "":
+		// integers and floats merge into numbers
+		s.name = widerNumber(s.name, name)
+		return true
+	}
+	s.kind, s.name, s.sample, s.fields, s.elem = kindMixed, "", nil, nil, nil
+	return false
+}
+
+// example returns a value like those observed: the first scalar, or an
+// empty object or array.
+func (s *shape) example() interface{} {
+	switch s.kind {
+	case kindScalar:
+		if c, ok := s.sample.(csvCell); ok {
+			return s.cells.value(string(c))
+		}
+		return s.sample
+	case kindObject:
+		return map[string]interface{}{}
+	case kindArray:
+		return []interface{}{}
+	case kindMap:
+		return keyedMap{key: s.name}
+	}
+	return nil
+}
+
+// values returns the number of non-null values observed.
+func (s *shape) values() int {
+	return s.seen - s.nulls
+}
+
+// modelBuilder converts shapes into a Model.
+type modelBuilder struct {
+	opts     Options
+	named    map[string]*Struct // named structs by key, when opts.SubStruct is set
+	structs  []*Struct
+	warnings []string
+	err      error
+	root     string          // name of the top-level type being built
+	imports  map[string]bool // packages of the scalar types used
+	xml      bool            // keys are xml tag names, see ParseXml
+	tuples   bool            // arrays may be tuples, see tupleFor
+
+	convertFloats bool // of the root being built
+
+	unresolved []string        // locations typed as placeholders, see Placeholder
+	todo       map[string]bool // unresolved locations by path
+	unknown    string          // name of the type of NamedPlaceholder
+
+	folds    map[*shape][]*shape // object shapes folded into each, see findFolds
+	folded   map[*shape]*shape   // the shape each folded shape folds into
+	building map[*shape]*Struct  // structs of the shapes folded into, by shape
+}
+
+func newModel(roots []*root, opts Options) (*Model, error) {
+	if opts.ForceFloats || ForceFloats {
+		// the deprecated forms of FloatNumbers
+		opts.Numbers = FloatNumbers
+	}
+	b := &modelBuilder{
+		opts:     opts,
+		imports:  make(map[string]bool),
+		folds:    make(map[*shape][]*shape),
+		folded:   make(map[*shape]*shape),
+		building: make(map[*shape]*Struct),
+		todo:     make(map[string]bool),
+	}
+	for _, t := range opts.Tags {
+		b.xml = b.xml || t == "xml"
+		b.tuples = b.tuples || t == "json"
+	}
+	if opts.SubStruct {
+		b.named = make(map[string]*Struct)
+	}
+
+	m := &Model{
+		Package: opts.Package,
+		Tags:    opts.Tags,
+	}
+	if opts.Placeholder == NamedPlaceholder {
+		b.unknown = "Unknown"
+		for i := 2; hasRoot(roots, b.unknown); i++ {
+			b.unknown = fmt.Sprintf("Unknown%v", i)
+		}
+	}
+	for _, r := range roots {
+		b.root = r.name
+		b.convertFloats = r.convertFloats
+		d := &Decl{Name: r.name, Path: r.sel.String()}
+		if opts.Comments {
+			where := b.where(r.sel)
+			if r.origin != "" {
+				where = r.origin
+				if opts.Source != "" {
+					where += " in " + opts.Source
+				}
+			}
+			d.Doc = fmt.Sprintf("%s was inferred from %s.", r.name, where)
+		}
+		b.findFolds(r.shape)
+		if r.shape.kind == kindObject {
+			if b.folds[r.shape] != nil {
+				b.building[r.shape] = &Struct{Name: r.name}
+			}
+			st := b.structFor(r.shape, r.sel)
+			st.Name = r.name
+			st.Doc = d.Doc
+			d.Type = &Type{Struct: st}
+			m.Structs = append(m.Structs, st)
+		} else {
+			d.Type = b.typeFor(r.shape, r.sel)
+			// a defined type would not have the methods of a tuple
+			d.Alias = d.Type.Struct != nil && d.Type.Struct.Tuple
+		}
+		m.Types = append(m.Types, d)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.unknown != "" && len(b.unresolved) > 0 {
+		d := &Decl{Name: b.unknown, Type: &Type{Name: "interface{}"}}
+		d.Doc = fmt.Sprintf(unknownDoc, d.Name)
+		m.Types = append(m.Types, d)
+	}
+	m.Unresolved = b.unresolved
+	if b.named != nil && len(m.Types) > 1 {
+		b.nameShared(m.Types)
+	}
+
+	sort.Slice(b.structs, func(i, j int) bool {
+		return b.structs[i].key() < b.structs[j].key()
+	})
+	m.Structs = append(m.Structs, b.structs...)
+	if opts.generics() && len(m.Types) > 1 {
+		b.extractEnvelopes(m)
+	}
+	if opts.Bases.Threshold > 0 {
+		b.extractBases(m)
+	}
+	for pkg := range b.imports {
+		m.Imports = append(m.Imports, pkg)
+	}
+	sort.Strings(m.Imports)
+	m.Warnings = b.warnings
+	return m, nil
+}
+
+// typeFor returns the Go type of the values described by s, found at path
+// in the input.
+func (b *modelBuilder) typeFor(s *shape, p path) *Type {
+	if s.deep {
+		b.warn("%s: not inspected beyond depth %d", p, b.opts.Limits.MaxDepth)
+		return &Type{Name: "interface{}"}
+	}
+
+	switch s.kind {
+	case kindScalar:
+		name := s.name
+		if name == csvCellType {
+			name = s.cells.typeName()
+			if name == "int64" || name == "float64" {
+				name = b.numberType(s, name, name == "int64")
+			}
+		} else if numberKinds[name] != 0 {
+			// untyped numbers are integers if every one is integral
+			name = b.numberType(s, name, !isFloat(name) || b.convertFloats && s.fraction == 0)
+		}
+		return b.use(name)
+	case kindArray:
+		if t := b.tupleFor(s, p); t != nil {
+			return t
+		}
+		elem := s.elem
+		if s.skipped > 0 {
+			inspected := 0
+			if elem != nil {
+				inspected = elem.seen
+			}
+			b.warn("%s: inspected %d of %d elements", p, inspected, inspected+s.skipped)
+		}
+		if elem == nil || elem.seen == 0 {
+			return b.emptyArray(p)
+		}
+		if elem.nulls > 0 && elem.values() > 0 {
+			return &Type{Elem: &Type{Name: "interface{}"}}
+		}
+		return &Type{Elem: b.elemFor(elem, p.elem())}
+	case kindMap:
+		elem := &Type{Name: "interface{}"}
+		if s.elem.seen > 0 && (s.elem.nulls == 0 || s.elem.values() == 0) {
+			elem = b.elemFor(s.elem, p.elem())
+		}
+		return &Type{Key: b.use(s.name), Elem: elem}
+	case kindObject:
+		if a, ok := b.folded[s]; ok {
+			// a struct may only hold itself through a pointer
+			return &Type{Struct: b.building[a], Pointer: true}
+		}
+		if b.folds[s] != nil {
+			st := b.recursive(s)
+			b.structFor(s, p)
+			if b.opts.Comments {
+				st.Doc = fmt.Sprintf("%s was inferred from %s.", st.Name, b.where(p))
+			}
+			return &Type{Struct: st}
+		}
+		if len(s.fields) == 0 && s.dropped == 0 && len(p) > 0 {
+			if t := b.emptyObject(p); t != nil {
+				return t
+			}
+		}
+		st := b.structFor(s, p)
+		named := b.name(st)
+		if named == st && st.Name != "" && b.opts.Comments {
+			st.Doc = fmt.Sprintf("%s was inferred from %s.", st.Name, b.where(p))
+		}
+		return &Type{Struct: named}
+	}
+	return &Type{Name: "interface{}"}
+}
+
+// elemFor returns the Go type of the elements of a slice or map, described
+// by s, which need no pointer to hold the struct enclosing them.
+func (b *modelBuilder) elemFor(s *shape, p path) *Type {
+	t := b.typeFor(s, p)
+	t.Pointer = false
+	return t
+}
+
+// packagePaths maps the names of the packages of scalar types to their
+// import paths, where they differ.
+var packagePaths = map[string]string{
+	"json": "encoding/json",
+	"xml":  "encoding/xml",
+}
+
+// use returns the type called name, such as "int" or "time.Time", and
+// records the package it is declared in.
+func (b *modelBuilder) use(name string) *Type {
+	if path := importPath(name); path != "" {
+		b.imports[path] = true
+	}
+	return &Type{Name: name}
+}
+
+// importPath returns the import path of the package the type called name
+// is declared in, or "" for builtin types.
+func importPath(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return ""
+	}
+	pkg := name[:i]
+	if path, ok := packagePaths[pkg]; ok {
+		return path
+	}
+	return pkg
+}
+
+// structFor builds the struct for an object shape, with fields sorted by key.
+func (b *modelBuilder) structFor(s *shape, p path) *Struct {
+	keys := make([]string, 0, len(s.fields))
+	for key := range s.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if b.xml && s.fields[xmlNameKey] != nil {
+		// the name of the root element goes first
+		i := sort.SearchStrings(keys, xmlNameKey)
+		copy(keys[1:i+1], keys[:i])
+		keys[0] = xmlNameKey
+	}
+
+	if s.dropped > 0 {
+		b.warn("%s: ignored keys beyond the first %d (%d values)", p, b.opts.Limits.MaxKeys, s.dropped)
+	}
+
+	st := b.building[s]
+	if st == nil {
+		st = &Struct{}
+	}
+	names := make(map[string]string)
+	for _, key := range keys {
+		f := s.fields[key]
+		fp := p.key(key)
+
+		name := FmtFieldName(key)
+		if b.xml {
+			name = xmlFieldName(key)
+			if _, ok := names[name]; ok && strings.HasSuffix(key, xmlAttrSuffix) {
+				name += "Attr"
+			}
+		}
+		if other, ok := names[name]; ok && name != "_" {
+			b.fail(&PathError{Path: fp.String(), Reason: fmt.Sprintf("field name %s is also used for key %q", name, other)})
+		}
+		names[name] = key
+
+		tagList := make([]string, 0)
+		for _, t := range b.opts.Tags {
+			switch {
+			case b.xml && key == xmlNameKey:
+				if t == "xml" {
+					tagList = append(tagList, fmt.Sprintf("%s:\"%v\"", t, f.sample))
+				}
+			case !b.xml || t == "xml":
+				tagList = append(tagList, fmt.Sprintf("%s:\"%s\"", t, key))
+			default:
+				tagList = append(tagList, fmt.Sprintf("%s:\"%s\"", t, xmlLocalName(key)))
+			}
+		}
+
+		var typ *Type
+		if b.xml && key == xmlNameKey {
+			typ = b.use("xml.Name")
+		} else {
+			typ = b.typeFor(f, fp)
+		}
+
+		field := &Field{
+			Name:     name,
+			Key:      key,
+			Type:     typ,
+			Tag:      strings.Join(tagList, " "),
+			Optional: b.optional(s, key),
+			Path:     fp.String(),
+			path:     fp,
+			sample:   f.example(),
+		}
+		field.Doc = f.doc
+		if b.opts.Placeholder == TODOPlaceholder && b.todo[fp.String()] {
+			field.Doc = strings.TrimPrefix(field.Doc+"\nTODO: always empty in the samples, so its type is unknown.", "\n")
+		}
+		if b.opts.Comments {
+			field.Doc = strings.TrimPrefix(field.Doc+"\n"+fieldDoc(f, s.values()), "\n")
+		}
+		st.Fields = append(st.Fields, field)
+	}
+	return st
+}
+
+func (b *modelBuilder) warn(format string, args ...interface{}) {
+	b.warnings = append(b.warnings, fmt.Sprintf(format, args...))
+}
+
+// fail records the first error found building the model.
+func (b *modelBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = withFile(err, b.opts.Source)
+	}
+}
+
+// where describes the location of p for doc comments.
+func (b *modelBuilder) where(p path) string {
+	switch {
+	case b.opts.Source == "":
+		return p.String()
+	case len(p) == 0:
+		return b.opts.Source
+	}
+	return p.String() + " in " + b.opts.Source
+}
+
+// nameShared renames the named structs of a model of several types so
+// that their names do not depend on the order the types were built in:
+// those that more than one of types use are named after the fields holding
+// them, e.g. Owner, and the others are numbered after the one type using
+// them.
+func (b *modelBuilder) nameShared(types []*Decl) {
+	sub := make(map[*Struct]bool)
+	for _, st := range b.structs {
+		sub[st] = true
+	}
+	users := make(map[*Struct]map[string]bool) // types using each struct
+	fields := make(map[*Struct]map[string]int) // fields holding each struct
+	for _, d := range types {
+		seen := make(map[*Struct]bool)
+		var walk func(t *Type, field string)
+		walk = func(t *Type, field string) {
+			for ; t != nil; t = t.Elem {
+				if t.Key != nil {
+					walk(t.Key, field)
+				}
+				st := t.Struct
+				if st == nil || seen[st] {
+					continue
+				}
+				if sub[st] {
+					if users[st] == nil {
+						users[st] = make(map[string]bool)
+						fields[st] = make(map[string]int)
+					}
+					users[st][d.Name] = true
+					fields[st][field]++
+				}
+				seen[st] = true
+				for _, f := range st.Fields {
+					walk(f.Type, f.Name)
+				}
+			}
+		}
+		walk(d.Type, d.Name)
+	}
+
+	taken := make(map[string]bool)
+	for _, d := range types {
+		taken[d.Name] = true
+	}
+	var shared []*Struct
+	counts := make(map[string]int)
+	for _, st := range b.structs {
+		if len(users[st]) < 2 {
+			for user := range users[st] {
+				counts[user]++
+				rename(st, fmt.Sprintf("%v_sub%v", user, counts[user]))
+			}
+			taken[st.Name] = true
+			continue
+		}
+		shared = append(shared, st)
+	}
+
+	// shared structs are named in order of their shape, and of the fields
+	// holding them, never of the names they had
+	names := make([]string, len(b.structs))
+	for i, st := range b.structs {
+		names[i], st.Name = st.Name, ""
+	}
+	shapes := make(map[*Struct]string)
+	for _, st := range shared {
+		shapes[st] = st.key()
+	}
+	for i, st := range b.structs {
+		st.Name = names[i]
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		return shapes[shared[i]] < shapes[shared[j]]
+	})
+
+	for _, st := range shared {
+		var best string
+		for field, n := range fields[st] {
+			if best == "" || n > fields[st][best] || n == fields[st][best] && field < best {
+				best = field
+			}
+		}
+		name := best
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%v%v", best, i)
+		}
+		taken[name] = true
+		rename(st, name)
+	}
+}
+
+// rename renames st, and its doc comment along with it.
+func rename(st *Struct, name string) {
+	if strings.HasPrefix(st.Doc, st.Name+" ") {
+		st.Doc = name + st.Doc[len(st.Name):]
+	}
+	st.Name = name
+}
+
+// forceName names st even if sub-structs are not enabled, for structs that
+// must be named, such as those with methods.
+func (b *modelBuilder) forceName(st *Struct) *Struct {
+	if b.named != nil {
+		return b.name(st)
+	}
+	st.Name = fmt.Sprintf("%v_sub%v", b.root, len(b.structs)+1)
+	b.structs = append(b.structs, st)
+	return st
+}
+
+// name gives st a name of its own if sub-structs are enabled, reusing an
+// existing struct of identical shape.
+func (b *modelBuilder) name(st *Struct) *Struct {
+	if b.named == nil {
+		return st
+	}
+	key := st.key()
+	if named, ok := b.named[key]; ok {
+		return named
+	}
+	st.Name = fmt.Sprintf("%v_sub%v", b.root, len(b.named)+1)
+	b.named[key] = st
+	b.structs = append(b.structs, st)
+	return st
+}
@@ -0,0 +1,192 @@
+package gojson
+
+import (
+	"fmt"
+	"math"
+)
+
+// A NumberPolicy chooses the Go types of numbers, in every input format.
+// Whatever the policy, numbers are only integers if every value seen is
+// integral, so integers and floats seen at one position, such as those of
+// the YAML list [1, 1.5], make up a float64. Integers above math.MaxInt64
+// make a uint64, or a float64, which rounds them, if some are negative.
+type NumberPolicy int
+
+const (
+	// Int64Numbers makes integral numbers whose type the input does not
+	// tell, such as those of JSON and CSV, int64, keeps the integer types
+	// of other formats, e.g. int for YAML, and makes others float64.
+	Int64Numbers NumberPolicy = iota
+	// SmallestNumbers makes integral numbers the smallest of int8 to
+	// int64, or of uint8 to uint64 if none is negative, that holds every
+	// value seen, and others float64.
+	SmallestNumbers
+	// FloatNumbers makes every number float64.
+	FloatNumbers
+)
+
+var numberPolicies = []string{"int64", "smallest", "float64"}
+
+// String returns the name of p, as accepted by UnmarshalText.
+func (p NumberPolicy) String() string {
+	if p < 0 || int(p) >= len(numberPolicies) {
+		return fmt.Sprintf("NumberPolicy(%d)", int(p))
+	}
+	return numberPolicies[p]
+}
+
+// UnmarshalText sets p to the policy named text: "int64", "smallest" or
+// "float64".
+func (p *NumberPolicy) UnmarshalText(text []byte) error {
+	for i, name := range numberPolicies {
+		if string(text) == name {
+			*p = NumberPolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid number policy %q, must be int64, smallest or float64", text)
+}
+
+// numberType returns the Go type of the numbers of s, as chosen by the
+// number policy. name is the type the input gives them, which is float64
+// for untyped numbers such as those of JSON, and integer tells whether
+// every one of them is an integer.
+func (b *modelBuilder) numberType(s *shape, name string, integer bool) string {
+	policy := b.opts.Numbers
+	switch {
+	case policy == FloatNumbers:
+		return "float64"
+	case !integer:
+		return name
+	case policy == SmallestNumbers:
+		return smallestInt(s.min, s.max, s.big > 0)
+	case s.big > 0 && numberKinds[name] != unsignedNumber:
+		// too large for int64
+		if s.min >= 0 {
+			return "uint64"
+		}
+		return "float64"
+	case isFloat(name):
+		// untyped integers
+		return "int64"
+	}
+	return name
+}
+
+// numeric tracks the numbers observed at one position, whatever their Go
+// types, for the number policy.
+type numeric struct {
+	numbers  int     // number of numeric scalars observed
+	fraction int     // number of them with a fractional part
+	big      int     // number of them above math.MaxInt64
+	min, max float64 // range of the numeric scalars
+}
+
+// number records the number v, which converts to n.
+func (s *numeric) number(v interface{}, n float64) {
+	if !integral(n) {
+		s.fraction++
+	}
+	if aboveInt64(v, n) {
+		s.big++
+	}
+	if s.numbers == 0 || n < s.min {
+		s.min = n
+	}
+	if s.numbers == 0 || n > s.max {
+		s.max = n
+	}
+	s.numbers++
+}
+
+// widerNumber returns the name of the type that numbers of the types named
+// a and b merge into, or "" if either is not a number type: uint64 for
+// unsigned integers, int64 for signed ones or a mix of both, and float64
+// otherwise. numberType checks that the integers merged into an int64 fit.
+func widerNumber(a, b string) string {
+	ka, kb := numberKinds[a], numberKinds[b]
+	switch {
+	case ka == 0 || kb == 0:
+		return ""
+	case a == b:
+		return a
+	case ka == unsignedNumber && kb == unsignedNumber:
+		return "uint64"
+	case ka != floatNumber && kb != floatNumber:
+		return "int64"
+	}
+	return "float64"
+}
+
+// isFloat reports whether the type named name is a floating-point type.
+func isFloat(name string) bool {
+	return numberKinds[name] == floatNumber
+}
+
+// A numberKind tells unsigned integers, signed ones and floats apart.
+type numberKind int
+
+const (
+	unsignedNumber numberKind = 1 + iota
+	signedNumber
+	floatNumber
+)
+
+// numberKinds classifies the number types by name.
+var numberKinds = map[string]numberKind{
+	"uint": unsignedNumber, "uint8": unsignedNumber, "uint16": unsignedNumber, "uint32": unsignedNumber, "uint64": unsignedNumber,
+	"int": signedNumber, "int8": signedNumber, "int16": signedNumber, "int32": signedNumber, "int64": signedNumber,
+	"float32": floatNumber, "float64": floatNumber,
+}
+
+// aboveInt64 reports whether the number v, which converts to n, is greater
+// than math.MaxInt64. Unsigned integers are compared exactly, as n may be
+// rounded to 2^63.
+func aboveInt64(v interface{}, n float64) bool {
+	switch v := v.(type) {
+	case uint64:
+		return v > math.MaxInt64
+	case uint:
+		return uint64(v) > math.MaxInt64
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return false
+	}
+	return n >= 1<<63
+}
+
+// integral reports whether f has no fractional part, give or take the
+// rounding errors of decimal input.
+func integral(f float64) bool {
+	const epsilon = .0001
+	return math.Abs(f-math.Floor(f+epsilon)) < epsilon
+}
+
+// smallestInt returns the smallest integer type that holds every integer
+// from min to max, or float64 if none does. big tells whether max is above
+// math.MaxInt64, which max itself may be rounded to.
+func smallestInt(min, max float64, big bool) string {
+	if min >= 0 {
+		switch {
+		case max <= math.MaxUint8:
+			return "uint8"
+		case max <= math.MaxUint16:
+			return "uint16"
+		case max <= math.MaxUint32:
+			return "uint32"
+		case max < math.MaxUint64:
+			return "uint64"
+		}
+		return "float64"
+	}
+	switch {
+	case min >= math.MinInt8 && max <= math.MaxInt8:
+		return "int8"
+	case min >= math.MinInt16 && max <= math.MaxInt16:
+		return "int16"
+	case min >= math.MinInt32 && max <= math.MaxInt32:
+		return "int32"
+	case min >= math.MinInt64 && !big:
+		return "int64"
+	}
+	return "float64"
+}
@@ -0,0 +1,133 @@
+package gojson
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestNumberPolicy tests that numbers get the type the policy chooses given every value seen
+func TestNumberPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		policy   NumberPolicy
+		expected string
+	}{
+		{`[1, 2.5]`, Int64Numbers, "float64"},
+		{`[2.5, 1]`, Int64Numbers, "float64"},
+		{`[1, 300]`, Int64Numbers, "int64"},
+		{`[1, 200]`, SmallestNumbers, "uint8"},
+		{`[1, 300]`, SmallestNumbers, "uint16"},
+		{`[1, 70000]`, SmallestNumbers, "uint32"},
+		{`[1, 5000000000]`, SmallestNumbers, "uint64"},
+		{`[-1, 100]`, SmallestNumbers, "int8"},
+		{`[-1, 200]`, SmallestNumbers, "int16"},
+		{`[-40000, 1]`, SmallestNumbers, "int32"},
+		{`[-5000000000, 1]`, SmallestNumbers, "int64"},
+		{`[1, 1.5]`, SmallestNumbers, "float64"},
+		{`[1, 2]`, FloatNumbers, "float64"},
+		{`[1, 10000000000000000000]`, Int64Numbers, "uint64"},
+		{`[-1, 10000000000000000000]`, Int64Numbers, "float64"},
+		{`[1, 10000000000000000000]`, SmallestNumbers, "uint64"},
+		{`[-1, 10000000000000000000]`, SmallestNumbers, "float64"},
+	}
+	for _, test := range tests {
+		m, err := Infer(strings.NewReader(test.input), ParseJson, Options{Name: "Foo", ConvertFloats: true, Numbers: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := m.Types[0].Type.Elem.Name; actual != test.expected {
+			t.Errorf("%s with %v: got %s, but expected %s", test.input, test.policy, actual, test.expected)
+		}
+	}
+
+	formats := []struct {
+		format   string
+		input    string
+		policy   NumberPolicy
+		expected string
+	}{
+		{"yaml", "a: [1, 1.5]\n", Int64Numbers, "float64"},
+		{"yaml", "a: [1, 2]\n", Int64Numbers, "int"},
+		{"yaml", "a: [1, 200]\n", SmallestNumbers, "uint8"},
+		{"yaml", "a: [1, 2]\n", FloatNumbers, "float64"},
+		{"yaml", "a: [1, 18446744073709551615]\n", Int64Numbers, "uint64"},
+		{"yaml", "a: [-1, 18446744073709551615]\n", Int64Numbers, "float64"}, // rounded
+		{"yaml", "a: [-1, 9223372036854775807]\n", SmallestNumbers, "int64"},
+		{"toml", "a = [1, 1.5]\n", Int64Numbers, "float64"},
+		{"toml", "a = [-1, 2]\n", SmallestNumbers, "int8"},
+		{"csv", "a\n1\n200\n", Int64Numbers, "int64"},
+		{"csv", "a\n1\n200\n", SmallestNumbers, "uint8"},
+		{"csv", "a\n1\n2.5\n", SmallestNumbers, "float64"},
+		{"csv", "a\n1\n2\n", FloatNumbers, "float64"},
+	}
+	for _, test := range formats {
+		f, _ := LookupFormat(test.format)
+		s, err := NewSampler(Options{Name: "Foo", Numbers: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sampleInput(s, strings.NewReader(test.input), f); err != nil {
+			t.Fatal(err)
+		}
+		m, err := s.Model()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := m.Structs[0].Fields[0].Type
+		if actual.Elem != nil {
+			actual = actual.Elem
+		}
+		if actual.Name != test.expected {
+			t.Errorf("%s %q with %v: got %s, but expected %s", test.format, test.input, test.policy, actual.Name, test.expected)
+		}
+	}
+
+	// unsigned and signed integers merge into an int64 if they fit, rather
+	// than a float64 that rounds integers above 2^53
+	values := []struct {
+		values   []interface{}
+		expected string
+	}{
+		{[]interface{}{uint64(9007199254740993), int64(-1)}, "int64"},
+		{[]interface{}{int64(-1), uint32(1)}, "int64"},
+		{[]interface{}{uint64(9223372036854775808), uint8(1)}, "uint64"},
+		{[]interface{}{uint64(9223372036854775808), int8(1)}, "uint64"},
+		{[]interface{}{uint64(9223372036854775808), int8(-1)}, "float64"},
+	}
+	for _, test := range values {
+		s, err := NewSampler(Options{Name: "Foo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Value(map[string]interface{}{"a": test.values})
+		m, err := s.Model()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := m.Structs[0].Fields[0].Type.Elem.Name; actual != test.expected {
+			t.Errorf("%T and %T: got %s, but expected %s", test.values[0], test.values[1], actual, test.expected)
+		}
+	}
+
+	ForceFloats = true
+	m, err := Infer(strings.NewReader(`[1, 2]`), ParseJson, Options{Name: "Foo", ConvertFloats: true, Numbers: SmallestNumbers})
+	ForceFloats = false
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := m.Types[0].Type.Elem.Name; actual != "float64" {
+		t.Errorf("with ForceFloats: got %s, but expected float64", actual)
+	}
+
+	var opts struct {
+		Numbers NumberPolicy `yaml:"numbers"`
+	}
+	if err := yaml.Unmarshal([]byte("numbers: smallest"), &opts); err != nil || opts.Numbers != SmallestNumbers {
+		t.Errorf("got %v (%v), but expected smallest", opts.Numbers, err)
+	}
+	if err := yaml.Unmarshal([]byte("numbers: large"), &opts); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
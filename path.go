@@ -0,0 +1,171 @@
+package gojson
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identRegexp matches the keys that JSONPath expressions spell as .key.
+var identRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// A path locates values in the input, as a sequence of object keys and
+// array elements. Paths parsed from JSONPath expressions may also select
+// a single element of an array, or every value of an object.
+type path []step
+
+type step struct {
+	kind  stepKind
+	key   string
+	index int
+}
+
+type stepKind int
+
+const (
+	stepKey   stepKind = iota // the value of key in an object
+	stepElem                  // every element of an array, or value of an object
+	stepIndex                 // the element at index of an array
+)
+
+// key returns the path of key within the object at p.
+func (p path) key(key string) path {
+	return append(p[:len(p):len(p)], step{key: key})
+}
+
+// elem returns the path of the elements of the array at p.
+func (p path) elem() path {
+	return append(p[:len(p):len(p)], step{kind: stepElem})
+}
+
+// String formats p as a JSONPath expression, e.g. $.items[*]["user-id"].
+func (p path) String() string {
+	str := "$"
+	for _, s := range p {
+		switch {
+		case s.kind == stepElem:
+			str += "[*]"
+		case s.kind == stepIndex:
+			str += "[" + strconv.Itoa(s.index) + "]"
+		case identRegexp.MatchString(s.key):
+			str += "." + s.key
+		default:
+			str += "[" + strconv.Quote(s.key) + "]"
+		}
+	}
+	return str
+}
+
+// matches reports whether p selects the value at loc.
+func (p path) matches(loc []location) bool {
+	if len(p) != len(loc) {
+		return false
+	}
+	for i, s := range p {
+		switch s.kind {
+		case stepKey:
+			if loc[i].array || loc[i].key != s.key {
+				return false
+			}
+		case stepIndex:
+			if !loc[i].array || loc[i].index != s.index {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// repro returns a document with v at p.
+func (p path) repro(v interface{}) interface{} {
+	for i := len(p) - 1; i >= 0; i-- {
+		switch p[i].kind {
+		case stepKey:
+			v = map[string]interface{}{p[i].key: v}
+		case stepElem:
+			v = []interface{}{v}
+		case stepIndex:
+			elems := make([]interface{}, p[i].index+1)
+			elems[p[i].index] = v
+			v = elems
+		}
+	}
+	return v
+}
+
+// parsePath parses a JSONPath expression made of child selectors: .key,
+// ["key"] or ['key'], the wildcards .* and [*], and array indices [n].
+func parsePath(expr string) (path, error) {
+	fail := func(reason string) (path, error) {
+		return nil, fmt.Errorf("invalid path %q: %s", expr, reason)
+	}
+
+	rest := strings.TrimSpace(expr)
+	if !strings.HasPrefix(rest, "$") {
+		return fail("must start with $")
+	}
+	rest = rest[1:]
+
+	p := path{}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return fail("recursive descent is not supported")
+		case strings.HasPrefix(rest, ".*"):
+			p = p.elem()
+			rest = rest[2:]
+		case rest[0] == '.':
+			n := 1
+			for n < len(rest) && rest[n] != '.' && rest[n] != '[' {
+				n++
+			}
+			if n == 1 {
+				return fail("empty key")
+			}
+			p = p.key(rest[1:n])
+			rest = rest[n:]
+		case rest[0] == '[' && len(rest) > 1 && (rest[1] == '"' || rest[1] == '\''):
+			q := rest[1]
+			end := 2
+			for end < len(rest) && rest[end] != q {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end+1 >= len(rest) || rest[end+1] != ']' {
+				return fail("unterminated [")
+			}
+			key := strings.Replace(rest[2:end], "\\'", "'", -1)
+			if q == '"' {
+				unquoted, err := strconv.Unquote(rest[1 : end+1])
+				if err != nil {
+					return fail("bad string " + rest[1:end+1])
+				}
+				key = unquoted
+			}
+			p = p.key(key)
+			rest = rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return fail("unterminated [")
+			}
+			sel := strings.TrimSpace(rest[1:end])
+			if sel == "*" {
+				p = p.elem()
+			} else {
+				i, err := strconv.Atoi(sel)
+				if err != nil || i < 0 {
+					return fail("unsupported selector [" + sel + "]")
+				}
+				p = append(p, step{kind: stepIndex, index: i})
+			}
+			rest = rest[end+1:]
+		default:
+			return fail("unexpected " + strconv.Quote(rest[:1]))
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,112 @@
+package gojson
+
+import (
+	"fmt"
+)
+
+// A Placeholder chooses the Go types of the arrays and objects that were
+// empty in every sample, and so give nothing to infer their types from.
+type Placeholder int
+
+const (
+	// InterfacePlaceholder makes empty arrays []interface{} and empty
+	// objects struct{}.
+	InterfacePlaceholder Placeholder = iota
+	// RawPlaceholder makes empty arrays []json.RawMessage and empty
+	// objects json.RawMessage, so that their values are kept for later.
+	RawPlaceholder
+	// MapPlaceholder makes empty arrays []any and empty objects
+	// map[string]any, spelled interface{} before Go 1.18.
+	MapPlaceholder
+	// TODOPlaceholder types them as InterfacePlaceholder does, with a
+	// TODO comment on the fields holding them.
+	TODOPlaceholder
+	// NamedPlaceholder declares a type Unknown for them, so that they are
+	// easily found: empty arrays are []Unknown and empty objects Unknown.
+	NamedPlaceholder
+)
+
+var placeholders = []string{"interface", "raw", "map", "todo", "named"}
+
+// String returns the name of p, as accepted by UnmarshalText.
+func (p Placeholder) String() string {
+	if p < 0 || int(p) >= len(placeholders) {
+		return fmt.Sprintf("Placeholder(%d)", int(p))
+	}
+	return placeholders[p]
+}
+
+// UnmarshalText sets p to the placeholder named text: "interface", "raw",
+// "map", "todo" or "named".
+func (p *Placeholder) UnmarshalText(text []byte) error {
+	for i, name := range placeholders {
+		if string(text) == name {
+			*p = Placeholder(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid placeholder %q, must be interface, raw, map, todo or named", text)
+}
+
+// unknownDoc documents the type NamedPlaceholder declares.
+const unknownDoc = "%s stands for the values that were empty in every sample, whose types\nare therefore unknown."
+
+// emptyArray returns the type of an array that was empty in every sample,
+// found at p, and reports p as unresolved.
+func (b *modelBuilder) emptyArray(p path) *Type {
+	b.unresolve(p, "always an empty array")
+	elem := "interface{}"
+	switch b.opts.Placeholder {
+	case RawPlaceholder:
+		elem = "json.RawMessage"
+	case MapPlaceholder:
+		elem = b.any()
+	case NamedPlaceholder:
+		elem = b.unknown
+	}
+	return &Type{Elem: b.use(elem)}
+}
+
+// emptyObject returns the type of an object that was empty in every
+// sample, found at p, and reports p as unresolved, or returns nil if the
+// placeholder is an empty struct.
+func (b *modelBuilder) emptyObject(p path) *Type {
+	b.unresolve(p, "always an empty object")
+	switch b.opts.Placeholder {
+	case RawPlaceholder:
+		return b.use("json.RawMessage")
+	case MapPlaceholder:
+		return &Type{Key: b.use("string"), Elem: b.use(b.any())}
+	case NamedPlaceholder:
+		return b.use(b.unknown)
+	}
+	return nil
+}
+
+// unresolve records that the type of the values at p is a placeholder,
+// and so that of the field holding them.
+func (b *modelBuilder) unresolve(p path, reason string) {
+	b.unresolved = append(b.unresolved, p.String()+": "+reason)
+	for len(p) > 0 && p[len(p)-1].kind != stepKey {
+		p = p[:len(p)-1]
+	}
+	b.todo[p.String()] = true
+}
+
+// any returns the name of the empty interface.
+func (b *modelBuilder) any() string {
+	if b.opts.generics() {
+		return "any"
+	}
+	return "interface{}"
+}
+
+// hasRoot reports whether one of roots is called name.
+func hasRoot(roots []*root, name string) bool {
+	for _, r := range roots {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package gojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPlaceholder tests that arrays and objects empty in every sample get the types the placeholder chooses, and are reported
+func TestPlaceholder(t *testing.T) {
+	input := `{"tags": [], "meta": {}, "items": [{}]}`
+	tests := []struct {
+		placeholder Placeholder
+		expected    []string // types of items, meta and tags
+	}{
+		{InterfacePlaceholder, []string{"[]struct {}", "struct {}", "[]interface{}"}},
+		{RawPlaceholder, []string{"[]json.RawMessage", "json.RawMessage", "[]json.RawMessage"}},
+		{MapPlaceholder, []string{"[]map[string]interface{}", "map[string]interface{}", "[]interface{}"}},
+		{TODOPlaceholder, []string{"[]struct {}", "struct {}", "[]interface{}"}},
+		{NamedPlaceholder, []string{"[]Unknown", "Unknown", "[]Unknown"}},
+	}
+	for _, test := range tests {
+		m, err := Infer(strings.NewReader(input), ParseJson, Options{Name: "Foo", Tags: []string{"json"}, Placeholder: test.placeholder})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []string
+		for _, f := range m.Types[0].Type.Struct.Fields {
+			actual = append(actual, f.Type.String())
+			if todo := strings.HasPrefix(f.Doc, "TODO"); todo != (test.placeholder == TODOPlaceholder) {
+				t.Errorf("%v: got doc %q for %s", test.placeholder, f.Doc, f.Name)
+			}
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%v: got %v, but expected %v", test.placeholder, actual, test.expected)
+		}
+		expected := []string{
+			"$.items[*]: always an empty object",
+			"$.meta: always an empty object",
+			"$.tags: always an empty array",
+		}
+		if !reflect.DeepEqual(m.Unresolved, expected) {
+			t.Errorf("%v: got unresolved %q, but expected %q", test.placeholder, m.Unresolved, expected)
+		}
+	}
+
+	m, err := Infer(strings.NewReader(input), ParseJson, Options{Name: "Foo", Placeholder: NamedPlaceholder, GoVersion: "1.18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Types) != 2 || m.Types[1].Name != "Unknown" || m.Types[1].Type.String() != "interface{}" {
+		t.Errorf("expected type Unknown to be declared, got %v", m.Types)
+	}
+}
+
+// TestPlaceholderResolved tests that arrays and objects empty in some samples take their types from the others
+func TestPlaceholderResolved(t *testing.T) {
+	input := `[{"tags": [], "meta": {}}, {"tags": ["a"], "meta": {"id": 1}}]`
+	m, err := Infer(strings.NewReader(input), ParseJson, Options{Name: "Foo", Package: "main", ConvertFloats: true, Placeholder: NamedPlaceholder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Unresolved) != 0 || len(m.Types) != 1 {
+		t.Errorf("expected no placeholders, got %q", m.Unresolved)
+	}
+	src, err := m.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "Tags []string") || !strings.Contains(string(src), "ID int64") {
+		t.Errorf("expected tags and meta to be typed from the second sample, got\n%s", src)
+	}
+}
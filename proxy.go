@@ -0,0 +1,136 @@
+package gojson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+)
+
+// An HttpSampler accumulates the types of the JSON and YAML bodies of HTTP
+// requests and responses, into a type per endpoint and kind of body as
+// ParseHar does. It is safe for concurrent use.
+type HttpSampler struct {
+	mu sync.Mutex
+	s  *Sampler
+}
+
+// NewHttpSampler returns an HttpSampler with no samples. Options.Name,
+// Path and Selections are ignored, since types are named after endpoints.
+func NewHttpSampler(opts Options) (*HttpSampler, error) {
+	s, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &HttpSampler{s: s}, nil
+}
+
+// Request samples the body of a request of method to rawurl.
+func (h *HttpSampler) Request(method, rawurl, contentType string, body []byte) error {
+	return h.sample(method, rawurl, 0, contentType, body)
+}
+
+// Response samples the body of the response with the given status to a
+// request of method to rawurl.
+func (h *HttpSampler) Response(method, rawurl string, status int, contentType string, body []byte) error {
+	return h.sample(method, rawurl, status, contentType, body)
+}
+
+func (h *HttpSampler) sample(method, rawurl string, status int, contentType string, body []byte) error {
+	docs, err := endpointSamples(method, rawurl, status, contentType, body)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, doc := range docs {
+		h.s.sampleNamed(doc.(named))
+	}
+	return nil
+}
+
+// Model returns the types inferred from the bodies sampled so far.
+func (h *HttpSampler) Model() (*Model, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.s.Model()
+}
+
+// proxyURLKey is the context key of the URL a proxied request was made to,
+// before it was rewritten for the upstream.
+type proxyURLKey struct{}
+
+// Proxy returns a handler that forwards requests to upstream, sampling the
+// JSON and YAML bodies of the requests and of their responses into h. Such
+// bodies are read whole before they are forwarded, others are streamed
+// untouched. Bodies that fail to parse are logged to logger, or to the
+// standard logger if it is nil, and forwarded all the same.
+func Proxy(upstream *url.URL, h *HttpSampler, logger *log.Logger) http.Handler {
+	logf := log.Printf
+	if logger != nil {
+		logf = logger.Printf
+	}
+
+	rp := httputil.NewSingleHostReverseProxy(upstream)
+	rp.ErrorLog = logger
+	rp.ModifyResponse = func(resp *http.Response) error {
+		contentType := resp.Header.Get("Content-Type")
+		if parserFor(contentType) == nil {
+			return nil
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		decoded := body
+		switch resp.Header.Get("Content-Encoding") {
+		case "":
+		case "gzip":
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err == nil {
+				decoded, err = ioutil.ReadAll(zr)
+			}
+			if err != nil {
+				logf("gojson: %s %s: response body: %s", resp.Request.Method, resp.Request.URL, err)
+				return nil
+			}
+		default:
+			logf("gojson: %s %s: response body: unsupported Content-Encoding %q", resp.Request.Method, resp.Request.URL, resp.Header.Get("Content-Encoding"))
+			return nil
+		}
+
+		rawurl, _ := resp.Request.Context().Value(proxyURLKey{}).(string)
+		if err := h.Response(resp.Request.Method, rawurl, resp.StatusCode, contentType, decoded); err != nil {
+			logf("gojson: %s %s: response body: %s", resp.Request.Method, rawurl, err)
+		}
+		return nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rawurl := req.URL.String()
+		req = req.WithContext(context.WithValue(req.Context(), proxyURLKey{}, rawurl))
+
+		contentType := req.Header.Get("Content-Type")
+		if parserFor(contentType) != nil && req.Body != nil {
+			body, err := ioutil.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if err := h.Request(req.Method, rawurl, contentType, body); err != nil {
+				logf("gojson: %s %s: request body: %s", req.Method, rawurl, err)
+			}
+		}
+		rp.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,75 @@
+package gojson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestProxy tests that bodies forwarded by the proxy are sampled into types per endpoint
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case req.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			w.Write(bytes.Replace(body, []byte("{"), []byte(`{"id": 3, `), 1))
+		case strings.HasSuffix(req.URL.Path, "/404"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not found"}`))
+		default:
+			// compressed, as upstreams often are
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(`{"id": 1, "name": "Ann"}`))
+			zw.Close()
+		}
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+	h, err := NewHttpSampler(Options{Package: "gojson", Tags: []string{"json"}, ConvertFloats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(Proxy(u, h, nil))
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL+"/users", "application/json", strings.NewReader(`{"name": "Bob"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"id": 3, "name": "Bob"}` {
+		t.Errorf("got response %s", body)
+	}
+	for _, path := range []string{"/users/1", "/users/404"} {
+		resp, err := http.Get(proxy.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	m, err := h.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range m.Types {
+		names = append(names, d.Name)
+	}
+	if strings.Join(names, ",") != "GetUsersByIDError,GetUsersByIDResponse,PostUsersRequest,PostUsersResponse" {
+		t.Errorf("got types %v", names)
+	}
+	if _, err := m.Source(); err != nil {
+		t.Error(err)
+	}
+}
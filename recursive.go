@@ -0,0 +1,116 @@
+package gojson
+
+import (
+	"fmt"
+)
+
+// findFolds finds the objects within s, such as the replies of a comment,
+// whose shape is that of an object enclosing them, or a subset of it, so
+// that they are typed as that object's struct rather than nested again as
+// deep as the samples go. For an object to be taken for the one enclosing
+// it, the key leading from that one to it must repeat: it must have the key
+// itself, like a reply with replies, or be held by an object that does.
+func (b *modelBuilder) findFolds(s *shape) {
+	type ancestor struct {
+		s   *shape
+		key string // key of the field leading towards the object visited
+	}
+	edges := make(map[*shape]string) // key each folded shape was reached by
+	var walk func(s *shape, ancestors []ancestor)
+	walk = func(s *shape, ancestors []ancestor) {
+		if s == nil {
+			return
+		}
+		switch s.kind {
+		case kindArray, kindMap:
+			walk(s.elem, ancestors)
+			return
+		case kindObject:
+		default:
+			return
+		}
+
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			a, key := ancestors[i].s, ancestors[i].key
+			_, repeats := s.fields[key]
+			if folded, ok := b.folded[a]; ok && edges[a] == key {
+				repeats = true
+				a = folded
+			}
+			if !repeats || !subset(s, a) {
+				continue
+			}
+			if outer, ok := b.folded[a]; ok {
+				a = outer
+			}
+			b.folded[s] = a
+			b.folds[a] = append(b.folds[a], s)
+			edges[s] = key
+			break
+		}
+		for key, f := range s.fields {
+			walk(f, append(ancestors[:len(ancestors):len(ancestors)], ancestor{s, key}))
+		}
+	}
+	walk(s, nil)
+}
+
+// subset reports whether every key of the object shape s is a key of the
+// object shape a, with values of compatible shapes.
+func subset(s, a *shape) bool {
+	for key, f := range s.fields {
+		af, ok := a.fields[key]
+		if !ok || !compatible(f, af) {
+			return false
+		}
+	}
+	return true
+}
+
+// compatible reports whether values of shape s can be typed as those of
+// shape a.
+func compatible(s, a *shape) bool {
+	switch {
+	case s == nil || s.kind == kindNull:
+		return true
+	case a == nil || s.kind != a.kind || s.name != a.name:
+		return false
+	case s.kind == kindObject:
+		return subset(s, a)
+	case s.kind == kindArray, s.kind == kindMap:
+		return compatible(s.elem, a.elem)
+	}
+	return true
+}
+
+// recursive returns the struct of an object shape that others fold into,
+// named before it is built so that its fields can refer to it.
+func (b *modelBuilder) recursive(s *shape) *Struct {
+	st := &Struct{}
+	if b.named != nil {
+		st.Name = fmt.Sprintf("%v_sub%v", b.root, len(b.named)+1)
+		// recursive structs are never merged with others by shape
+		b.named["\x00"+st.Name] = st
+	} else {
+		st.Name = fmt.Sprintf("%v_sub%v", b.root, len(b.structs)+1)
+	}
+	b.structs = append(b.structs, st)
+	b.building[s] = st
+	return st
+}
+
+// optional reports whether the field key of the object shape s is missing
+// from, or null in, some of its values, including those folded into it.
+func (b *modelBuilder) optional(s *shape, key string) bool {
+	f := s.fields[key]
+	if f.seen < s.values() || f.nulls > 0 {
+		return true
+	}
+	for _, d := range b.folds[s] {
+		df := d.fields[key]
+		if df == nil || df.seen < d.values() || df.nulls > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package gojson
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRecursive tests that objects nesting the shape of an enclosing object are typed as that object's struct
+func TestRecursive(t *testing.T) {
+	tests := []struct {
+		input     string
+		subStruct bool
+		expected  string
+	}{
+		{
+			`{"id": 1, "text": "a", "author": {"id": 2, "login": "x"}, "parent": {"id": 0, "text": "p", "parent": {"id": -1, "text": "q"}}, "replies": [{"id": 2, "text": "b", "replies": [{"id": 3, "text": "c"}]}]}`,
+			false,
+			"package main\n\ntype Foo struct {\n\tAuthor struct {\n\t\tID    int64  `json:\"id\"`\n\t\tLogin string `json:\"login\"`\n\t} `json:\"author\"`\n\tID      int64  `json:\"id\"`\n\tParent  *Foo   `json:\"parent\"`\n\tReplies []Foo  `json:\"replies\"`\n\tText    string `json:\"text\"`\n}\n",
+		},
+		{
+			`[{"name": "/", "size": 1, "children": [{"name": "a", "size": 2, "children": [{"name": "b", "size": 3}]}]}]`,
+			true,
+			"package main\n\ntype Foo []Foo_sub1\n\ntype Foo_sub1 struct {\n\tChildren []Foo_sub1 `json:\"children\"`\n\tName     string     `json:\"name\"`\n\tSize     int64      `json:\"size\"`\n}\n",
+		},
+		{
+			// owner does not nest owners, so it is not taken for a Foo
+			`{"id": 1, "name": "a", "owner": {"id": 2, "name": "c"}}`,
+			false,
+			"package main\n\ntype Foo struct {\n\tID    int64  `json:\"id\"`\n\tName  string `json:\"name\"`\n\tOwner struct {\n\t\tID   int64  `json:\"id\"`\n\t\tName string `json:\"name\"`\n\t} `json:\"owner\"`\n}\n",
+		},
+	}
+	for _, test := range tests {
+		m, err := Infer(strings.NewReader(test.input), ParseJson, Options{Name: "Foo", Package: "main", Tags: []string{"json"}, SubStruct: test.subStruct, ConvertFloats: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := m.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(src) != test.expected {
+			t.Errorf("'%s' (expected) != '%s' (actual)", test.expected, src)
+		}
+	}
+
+	m, err := Infer(strings.NewReader(tests[0].input), ParseJson, Options{Name: "Foo", ConvertFloats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range m.Types[0].Type.Struct.Fields {
+		if f.Name == "Replies" && !f.Optional {
+			t.Error("expected Replies to be optional, as the innermost replies have none")
+		}
+	}
+}
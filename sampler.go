@@ -0,0 +1,813 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A StreamParser reads an input incrementally, reporting every value to a
+// Sampler as soon as it is decoded instead of building the whole document
+// in memory first.
+type StreamParser func(input io.Reader, s *Sampler) error
+
+// Limits bound the work done inferring types from large inputs. The zero
+// value imposes no limits.
+type Limits struct {
+	// MaxElements is the number of elements of each array that are
+	// inspected; the first ones, unless Reservoir is set.
+	MaxElements int
+	// Reservoir inspects MaxElements elements of each array chosen
+	// uniformly at random, holding them in memory until the array ends.
+	Reservoir bool
+	// Seed seeds the random choices of Reservoir.
+	Seed int64
+	// Every inspects only every Every-th element of each array.
+	Every int
+	// MaxDepth is the number of levels of nested objects and arrays that
+	// are inspected. Deeper ones become interface{}.
+	MaxDepth int
+	// MaxKeys is the number of distinct keys of each object that are
+	// inspected. Values of any further keys are ignored.
+	MaxKeys int
+}
+
+// A Sampler accumulates the types of the values reported to it. Values are
+// reported either whole, with Value, or piecewise with Scalar, Key and the
+// Begin/End methods. Every top-level value is merged into the same type, as
+// another sample of it.
+//
+// If Options select parts of the input with JSONPath expressions, only the
+// values matching them are sampled, each expression into a type of its own.
+type Sampler struct {
+	opts  Options
+	rand  *rand.Rand
+	roots []*root
+	named []*root    // roots of named samples
+	loc   []location // location of the current value
+}
+
+// location is an object or array the Sampler is in the middle of, and the
+// key or index of its current value.
+type location struct {
+	array bool
+	key   string
+	index int
+}
+
+// root samples the values matching a selection into a type of its own.
+type root struct {
+	name     string
+	origin   string // what the values are, if not the whole input
+	sel      path   // nil for the whole input
+	shape    *shape
+	selected bool    // a matching value is being reported
+	stack    []frame // objects and arrays within the matching value
+	rec      *recorder
+
+	// convertFloats makes numbers that look integral int64, see
+	// Options.ConvertFloats.
+	convertFloats bool
+
+	// With Options.GroupBy, matching values are recorded whole and then
+	// sampled into the group they belong to instead.
+	group  []string
+	groups map[string]*root
+}
+
+// frame is an object or array the Sampler is in the middle of.
+type frame struct {
+	object *shape // the object, nil for arrays
+	array  *shape // the array, nil for objects
+	target *shape // where the next value is merged into, nil to ignore it
+	ignore bool   // the object or array itself is ignored
+
+	// array sampling
+	index     int // elements seen
+	inspected int // elements merged into target
+	reservoir []interface{}
+	slot      int // reservoir slot of the element being recorded
+}
+
+// NewSampler returns an empty Sampler for the types described by opts.
+func NewSampler(opts Options) (*Sampler, error) {
+	if _, err := opts.goMinor(); err != nil {
+		return nil, err
+	}
+	s := &Sampler{
+		opts: opts,
+		rand: rand.New(rand.NewSource(opts.Limits.Seed)),
+	}
+
+	selections := opts.Selections
+	if len(selections) == 0 {
+		selections = []Selection{{Name: opts.Name, Path: opts.Path}}
+	}
+	for _, sel := range selections {
+		r := &root{name: sel.Name, shape: new(shape), convertFloats: opts.ConvertFloats}
+		if len(opts.GroupBy) > 0 {
+			r.group = opts.GroupBy
+			r.groups = make(map[string]*root)
+		}
+		if sel.Path != "" {
+			p, err := parsePath(sel.Path)
+			if err != nil {
+				return nil, err
+			}
+			r.sel = p
+		}
+		s.roots = append(s.roots, r)
+	}
+	return s, nil
+}
+
+// begin starts reporting a value to the roots it is selected by, and
+// returns them.
+func (s *Sampler) begin() []*root {
+	if len(s.loc) > 0 && s.loc[len(s.loc)-1].array {
+		s.loc[len(s.loc)-1].index++
+	}
+	roots := make([]*root, 0, len(s.roots))
+	for _, r := range s.roots {
+		if !r.selected && r.sel.matches(s.loc) {
+			r.selected = true
+		}
+		if r.selected {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// active returns the roots a matching value is being reported to.
+func (s *Sampler) active() []*root {
+	roots := make([]*root, 0, len(s.roots))
+	for _, r := range s.roots {
+		if r.selected {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// Value reports a decoded value, which may be a scalar, nil, or a
+// map[string]interface{}, map[interface{}]interface{} or []interface{}.
+func (s *Sampler) Value(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s.object(v)
+	case map[interface{}]interface{}:
+		s.object(convertKeysToStrings(v))
+	case keyedMap:
+		s.BeginMap(v.key)
+		for _, key := range v.sortedKeys() {
+			s.Key(key)
+			s.Value(v.values[key])
+		}
+		s.EndMap()
+	case []interface{}:
+		s.BeginArray()
+		for _, e := range v {
+			s.Value(e)
+		}
+		s.EndArray()
+	case []map[string]interface{}:
+		// arrays of tables, as decoded from TOML
+		s.BeginArray()
+		for _, e := range v {
+			s.object(e)
+		}
+		s.EndArray()
+	default:
+		s.Scalar(v)
+	}
+}
+
+func (s *Sampler) object(obj map[string]interface{}) {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	s.BeginObject()
+	for _, key := range keys {
+		s.Key(key)
+		v := obj[key]
+		if d, ok := v.(documented); ok {
+			s.Doc(d.doc)
+			v = d.value
+		}
+		s.Value(v)
+	}
+	s.EndObject()
+}
+
+// Null reports a null value.
+func (s *Sampler) Null() {
+	s.Scalar(nil)
+}
+
+// Scalar reports a string, number, boolean or nil.
+func (s *Sampler) Scalar(v interface{}) {
+	for _, r := range s.begin() {
+		r.scalar(v, s)
+	}
+}
+
+// BeginObject reports the start of an object. Each of its values must be
+// preceded by a call to Key.
+func (s *Sampler) BeginObject() {
+	for _, r := range s.begin() {
+		r.beginObject(s)
+	}
+	s.loc = append(s.loc, location{})
+}
+
+// Key reports the key of the next value of the current object.
+func (s *Sampler) Key(key string) {
+	s.loc[len(s.loc)-1].key = key
+	for _, r := range s.active() {
+		r.key(key, s)
+	}
+}
+
+// Doc reports a comment documenting the current key, such as a comment
+// of a JSON5 document. The first comment reported for a key is kept.
+func (s *Sampler) Doc(doc string) {
+	for _, r := range s.active() {
+		r.doc(doc)
+	}
+}
+
+// EndObject reports the end of the current object.
+func (s *Sampler) EndObject() {
+	s.loc = s.loc[:len(s.loc)-1]
+	for _, r := range s.active() {
+		r.endObject(s)
+	}
+}
+
+// BeginMap reports the start of a map whose keys are of the Go type named
+// key, such as "int". Each of its values must be preceded by a call to Key,
+// and they are all of the same type.
+func (s *Sampler) BeginMap(key string) {
+	for _, r := range s.begin() {
+		r.beginMap(key, s)
+	}
+	s.loc = append(s.loc, location{})
+}
+
+// EndMap reports the end of the current map.
+func (s *Sampler) EndMap() {
+	s.loc = s.loc[:len(s.loc)-1]
+	for _, r := range s.active() {
+		r.endObject(s)
+	}
+}
+
+// BeginArray reports the start of an array.
+func (s *Sampler) BeginArray() {
+	for _, r := range s.begin() {
+		r.beginArray(s)
+	}
+	s.loc = append(s.loc, location{array: true, index: -1})
+}
+
+// EndArray reports the end of the current array.
+func (s *Sampler) EndArray() {
+	s.loc = s.loc[:len(s.loc)-1]
+	for _, r := range s.active() {
+		r.endArray(s)
+	}
+}
+
+// sample reports the result of a Parser, which may hold several samples,
+// some of them named.
+func (s *Sampler) sample(v interface{}) {
+	docs, ok := v.(samples)
+	if !ok {
+		docs = samples{v}
+	}
+	for _, doc := range docs {
+		if n, ok := doc.(named); ok {
+			s.sampleNamed(n)
+		} else {
+			s.Value(doc)
+		}
+	}
+}
+
+// sampleNamed samples the value of n into the type it names, whatever
+// the selections.
+func (s *Sampler) sampleNamed(n named) {
+	var r *root
+	for _, nr := range s.named {
+		if nr.name == n.name {
+			r = nr
+		}
+	}
+	if r == nil {
+		r = &root{name: n.name, origin: n.origin, shape: new(shape), convertFloats: s.opts.ConvertFloats}
+		s.named = append(s.named, r)
+	}
+	r.replay(n.value, s)
+}
+
+// Model returns the types inferred from the values reported so far.
+func (s *Sampler) Model() (*Model, error) {
+	roots, err := s.modelRoots()
+	if err != nil {
+		return nil, err
+	}
+	return newModel(roots, s.opts)
+}
+
+// modelRoots returns the roots a type is inferred for, in order.
+func (s *Sampler) modelRoots() ([]*root, error) {
+	roots := make([]*root, 0, len(s.roots)+len(s.named))
+	for _, r := range s.roots {
+		if len(s.named) > 0 && r.shape.kind == kindNull {
+			// only named samples were reported
+			continue
+		}
+		if len(r.groups) == 0 {
+			roots = append(roots, r)
+			continue
+		}
+		names := make([]string, 0, len(r.groups))
+		for name := range r.groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			roots = append(roots, r.groups[name])
+		}
+	}
+	named := append([]*root(nil), s.named...)
+	sort.Slice(named, func(i, j int) bool {
+		return named[i].name < named[j].name
+	})
+	roots = append(roots, named...)
+
+	for _, r := range roots {
+		switch {
+		case r.shape.kind == kindObject, r.shape.kind == kindArray:
+		case r.sel != nil && r.shape.kind == kindNull:
+			return nil, &PathError{File: s.opts.Source, Path: r.sel.String(), Reason: "no values match"}
+		case r.sel != nil && r.shape.kind == kindScalar:
+		case r.shape.kind == kindNull:
+			return nil, &SyntaxError{File: s.opts.Source, Reason: "empty input"}
+		case r.shape.kind == kindScalar:
+			return nil, &PathError{File: s.opts.Source, Path: "$", Reason: "expected an object or array, got " + r.shape.name}
+		default:
+			return nil, &PathError{File: s.opts.Source, Path: r.sel.String(), Reason: "values are of different types"}
+		}
+	}
+	return roots, nil
+}
+
+// InferStream reads input with parser and infers a Model of the Go types
+// that describe it, using memory proportional to the inferred types rather
+// than to the input.
+func InferStream(input io.Reader, parser StreamParser, opts Options) (*Model, error) {
+	s, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := parser(input, s); err != nil {
+		return nil, withFile(err, opts.Source)
+	}
+	return s.Model()
+}
+
+// next returns the shape the next value is merged into, or nil if it is to
+// be ignored, applying the sampling limits to array elements. Elements
+// chosen for a reservoir are recorded into r.rec rather than merged.
+func (r *root) next(s *Sampler) *shape {
+	if r.rec != nil {
+		return nil
+	}
+	if len(r.stack) == 0 {
+		if r.group != nil {
+			r.rec = new(recorder)
+			return nil
+		}
+		return r.shape
+	}
+	f := &r.stack[len(r.stack)-1]
+	if f.array == nil {
+		return f.target
+	}
+
+	l := s.opts.Limits
+	i := f.index
+	f.index++
+	if l.Every > 1 {
+		if i%l.Every != 0 {
+			return nil
+		}
+		i /= l.Every
+	}
+
+	switch {
+	case l.MaxElements <= 0:
+	case !l.Reservoir:
+		if i >= l.MaxElements {
+			return nil
+		}
+	default:
+		f.slot = i
+		if i >= l.MaxElements {
+			f.slot = s.rand.Intn(i + 1)
+			if f.slot >= l.MaxElements {
+				return nil
+			}
+		}
+		r.rec = new(recorder)
+		return nil
+	}
+	f.inspected++
+	return f.target
+}
+
+// deep reports whether a nested object or array starting now is beyond
+// the depth limit.
+func (r *root) deep(s *Sampler) bool {
+	return s.opts.Limits.MaxDepth > 0 && len(r.stack) >= s.opts.Limits.MaxDepth
+}
+
+// done ends the matching value once all its objects and arrays have ended.
+func (r *root) done() {
+	if len(r.stack) == 0 && r.rec == nil {
+		r.selected = false
+	}
+}
+
+// recorded stores the element recorded into r.rec once it is complete, or
+// samples the matching value into its group.
+func (r *root) recorded(s *Sampler) {
+	if !r.rec.done {
+		return
+	}
+	if len(r.stack) == 0 {
+		v := r.rec.value
+		r.rec = nil
+		r.route(v, s)
+		r.done()
+		return
+	}
+	f := &r.stack[len(r.stack)-1]
+	if f.slot < len(f.reservoir) {
+		f.reservoir[f.slot] = r.rec.value
+	} else {
+		f.reservoir = append(f.reservoir, r.rec.value)
+	}
+	r.rec = nil
+}
+
+func (r *root) scalar(v interface{}, s *Sampler) {
+	r.element(v, true)
+	if t := r.next(s); r.rec != nil {
+		r.rec.add(v)
+		r.recorded(s)
+	} else if t != nil && v == nil {
+		t.null()
+	} else if t != nil {
+		t.scalar(v)
+	}
+	r.done()
+}
+
+func (r *root) beginObject(s *Sampler) {
+	r.element(nil, false)
+	t := r.next(s)
+	if r.rec != nil {
+		r.rec.begin(map[string]interface{}{})
+		return
+	}
+	if t != nil && r.deep(s) {
+		t.truncate()
+		t = nil
+	}
+	if t != nil {
+		t.object()
+	}
+	r.stack = append(r.stack, frame{object: t, ignore: t == nil})
+}
+
+func (r *root) key(key string, s *Sampler) {
+	if r.rec != nil {
+		r.rec.key(key)
+		return
+	}
+	f := &r.stack[len(r.stack)-1]
+	if f.object != nil {
+		f.target = f.object.field(key, s.opts.Limits.MaxKeys)
+	}
+}
+
+func (r *root) doc(doc string) {
+	if r.rec != nil {
+		return
+	}
+	f := &r.stack[len(r.stack)-1]
+	if f.target != nil && f.target.doc == "" {
+		f.target.doc = doc
+	}
+}
+
+func (r *root) endObject(s *Sampler) {
+	if r.rec != nil {
+		r.rec.end()
+		r.recorded(s)
+		return
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+	r.done()
+}
+
+func (r *root) beginMap(key string, s *Sampler) {
+	r.element(nil, false)
+	t := r.next(s)
+	if r.rec != nil {
+		r.rec.begin(keyedMap{key: key, values: map[string]interface{}{}})
+		return
+	}
+	if t != nil && r.deep(s) {
+		t.truncate()
+		t = nil
+	}
+	f := frame{ignore: t == nil}
+	if t != nil {
+		f.target = t.mapOf(key)
+	}
+	r.stack = append(r.stack, f)
+}
+
+func (r *root) beginArray(s *Sampler) {
+	r.element(nil, false)
+	t := r.next(s)
+	if r.rec != nil {
+		r.rec.begin([]interface{}{})
+		return
+	}
+	if t != nil && r.deep(s) {
+		t.truncate()
+		t = nil
+	}
+	f := frame{array: t, ignore: t == nil}
+	if t != nil {
+		f.target = t.array()
+	}
+	r.stack = append(r.stack, f)
+}
+
+// endArray ends an array. Elements are merged one by one as they are
+// reported, except those held in a reservoir, which are merged now.
+func (r *root) endArray(s *Sampler) {
+	if r.rec != nil {
+		r.rec.end()
+		r.recorded(s)
+		return
+	}
+	f := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+	defer r.done()
+	if f.ignore {
+		return
+	}
+
+	if f.reservoir != nil {
+		// merge the reservoir as the elements of an unlimited array
+		r.stack = append(r.stack, frame{target: f.target})
+		for _, e := range f.reservoir {
+			r.replay(e, s)
+		}
+		r.stack = r.stack[:len(r.stack)-1]
+		f.inspected = len(f.reservoir)
+	}
+	f.array.skipped += f.index - f.inspected
+	f.array.endTuple(f.index)
+}
+
+// element records a value about to be reported, if it is an element of an
+// array, at its index for tuple detection. Values within elements that are
+// being recorded are not elements of the array.
+func (r *root) element(v interface{}, scalar bool) {
+	if r.rec != nil || len(r.stack) == 0 {
+		return
+	}
+	if f := r.stack[len(r.stack)-1]; f.array != nil {
+		f.array.position(f.index, v, scalar)
+	}
+}
+
+// replay reports a recorded value to r alone.
+func (r *root) replay(v interface{}, s *Sampler) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		r.beginObject(s)
+		for _, key := range keys {
+			r.key(key, s)
+			r.replay(v[key], s)
+		}
+		r.endObject(s)
+	case keyedMap:
+		r.beginMap(v.key, s)
+		for _, key := range v.sortedKeys() {
+			r.key(key, s)
+			r.replay(v.values[key], s)
+		}
+		r.endObject(s)
+	case []interface{}:
+		r.beginArray(s)
+		for _, e := range v {
+			r.replay(e, s)
+		}
+		r.endArray(s)
+	default:
+		r.scalar(v, s)
+	}
+}
+
+// route samples v into the group of r named after the values of its
+// r.group keys, or named like r if it has none of them.
+func (r *root) route(v interface{}, s *Sampler) {
+	name := ""
+	if obj, ok := v.(map[string]interface{}); ok {
+		for _, key := range r.group {
+			if val, ok := obj[key]; ok && val != nil {
+				name += groupName(fmt.Sprint(val))
+			}
+		}
+	}
+	if name == "" {
+		name = r.name
+	}
+
+	g := r.groups[name]
+	if g == nil {
+		g = &root{name: name, sel: r.sel, shape: new(shape), convertFloats: r.convertFloats}
+		r.groups[name] = g
+	}
+	g.replay(v, s)
+}
+
+// groupName formats a group value as part of a type name, one word per
+// run of letters and digits, so "apps/v1" becomes AppsV1.
+func groupName(val string) string {
+	name := ""
+	for _, word := range strings.FieldsFunc(val, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		name += FmtFieldName(word)
+	}
+	return name
+}
+
+// recorder rebuilds a value from the events reported for it.
+type recorder struct {
+	stack []*container
+	value interface{}
+	done  bool
+}
+
+// container is an object, map or array being recorded.
+type container struct {
+	object map[string]interface{}
+	array  []interface{}
+	key    string
+	keys   string // type of the keys of a map
+}
+
+func (r *recorder) add(v interface{}) {
+	if len(r.stack) == 0 {
+		r.value, r.done = v, true
+		return
+	}
+	c := r.stack[len(r.stack)-1]
+	if c.object != nil {
+		c.object[c.key] = v
+	} else {
+		c.array = append(c.array, v)
+	}
+}
+
+// begin starts recording a map[string]interface{}, keyedMap or
+// []interface{}.
+func (r *recorder) begin(v interface{}) {
+	c := new(container)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c.object = v
+	case keyedMap:
+		c.object, c.keys = v.values, v.key
+	case []interface{}:
+		c.array = v
+	}
+	r.stack = append(r.stack, c)
+}
+
+func (r *recorder) key(key string) {
+	r.stack[len(r.stack)-1].key = key
+}
+
+func (r *recorder) end() {
+	c := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+	switch {
+	case c.keys != "":
+		r.add(keyedMap{key: c.keys, values: c.object})
+	case c.object != nil:
+		r.add(c.object)
+	default:
+		r.add(c.array)
+	}
+}
+
+// StreamJson walks the tokens of a stream of JSON values, such as a single
+// document or newline delimited JSON.
+func StreamJson(input io.Reader, s *Sampler) error {
+	p := newPositionReader(input)
+	dec := json.NewDecoder(p)
+	for dec.More() {
+		start := dec.InputOffset()
+		if err := streamJsonValue(dec, s); err != nil {
+			// the value has started, so it cannot end cleanly
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if _, ok := err.(*json.SyntaxError); ok {
+				// Token checks delimiters apart from the values between
+				// them, and may blame a different byte than ParseJson.
+				// Decoding the value again reports the same error.
+				if b, ok := p.since(start); ok {
+					var raw json.RawMessage
+					if e, ok := json.NewDecoder(bytes.NewReader(b)).Decode(&raw).(*json.SyntaxError); ok {
+						return p.jsonSyntaxError(e, start)
+					}
+				}
+			}
+			return p.jsonError(err)
+		}
+	}
+	if tok, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return p.jsonError(err)
+		}
+		line, column := p.position(dec.InputOffset() - 1)
+		return &SyntaxError{Line: line, Column: column, Reason: fmt.Sprintf("unexpected %v", tok)}
+	}
+	return nil
+}
+
+func streamJsonValue(dec *json.Decoder, s *Sampler) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			s.BeginObject()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				s.Key(key.(string))
+				if err := streamJsonValue(dec, s); err != nil {
+					return err
+				}
+			}
+			s.EndObject()
+		} else {
+			s.BeginArray()
+			for dec.More() {
+				if err := streamJsonValue(dec, s); err != nil {
+					return err
+				}
+			}
+			s.EndArray()
+		}
+		// consume the closing delimiter
+		_, err = dec.Token()
+		return err
+	default:
+		s.Scalar(tok)
+	}
+	return nil
+}
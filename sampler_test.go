@@ -0,0 +1,303 @@
+package gojson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStreamJson tests that streaming a document infers the same types as decoding it whole
+func TestStreamJson(t *testing.T) {
+	for _, name := range []string{"example.json", "example_array.json", "comments.json"} {
+		opts := Options{Name: "Test", Package: "gojson", Tags: []string{"json"}, SubStruct: true, ConvertFloats: true, Comments: true}
+		expected, err := inferExample(t, name, opts).Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(filepath.Join("examples", name))
+		if err != nil {
+			t.Fatalf("error opening %s: %s", name, err)
+		}
+		m, err := InferStream(f, StreamJson, opts)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		actual, err := m.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(expected) {
+			t.Errorf("%s: '%s' (expected) != '%s' (actual)", name, expected, actual)
+		}
+	}
+}
+
+// TestStreamYaml tests that streaming YAML infers the same types, and reports the same errors, as decoding it whole
+func TestStreamYaml(t *testing.T) {
+	examples := []string{
+		"a:\n  b: 1\n  c: [1, 2]\nd:\n- 1\n- 2.5\n",
+		"- a: 1\n  b: two words\n- a: 3\n  c: {x: 1}\n- - 1\n  -\n    - 2\n",
+		"base: &b\n  x: 1\n  y: s\nother:\n  <<: *b\n  y: t\n  z: true\n",
+		"a: &a {k: 1}\nb: &b {j: 2}\nm:\n  <<: [*a, *b]\n  k: 3\n",
+		"inner: &i {v: 1}\nouter: &o\n  in: *i\n  w: 2\nuse:\n  - x: *o\n",
+		"list:\n- &item\n  name: a\n- *item\n",
+		"text: |\n  line one\n\n  line two\nnext: >-\n  folded\n  text\nbin: !!binary aGVsbG8=\n",
+		"# comment\n'quoted: key': 1 # trailing\nurl: http://x.y/z\ntime: 2020-01-01T00:00:00Z\n? complex\n: value\n",
+		"a: [1,\n  2, 3]\nb: \"multi\n  line\"\n",
+		"1: a\n2: b\n",
+		"a: 1\na:\n  b: 2\nc: 3\n",
+		"%TAG !e! tag:example.com,2000:\n---\na: !e!foo 1\n---\n- 1\n...\n--- [1, 2]\n--- |\n  text\n",
+		"%YAML 1.2\n---\na: 1\n",
+		"a: 1\nb: [1\nc: 2\n",
+		"a: 1\nb\nc: 2\n",
+		"a:\n  b: 1\n c: 2\n",
+		"- a\nb: 1\n",
+		"a: *missing\n",
+	}
+	database, err := ioutil.ReadFile(filepath.Join("examples", "database.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples = append(examples, string(database))
+
+	yaml, _ := LookupFormat("yaml")
+	for i, in := range examples {
+		opts := Options{Name: "Test", Package: "gojson", Tags: []string{"yaml"}, SubStruct: true}
+		expected, err := InferFormat(strings.NewReader(in), yaml, opts)
+		m, serr := InferStream(strings.NewReader(in), StreamYaml, opts)
+		if err != nil || serr != nil {
+			if err == nil || serr == nil || err.Error() != serr.Error() {
+				t.Errorf("[Example %d] '%v' (expected) != '%v' (actual)", i+1, err, serr)
+			}
+			continue
+		}
+		expectedSource, err := expected.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := m.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(expectedSource) {
+			t.Errorf("[Example %d] '%s' (expected) != '%s' (actual)", i+1, expectedSource, actual)
+		}
+	}
+}
+
+// TestStreamSamples tests that every value of a stream is merged as a sample
+func TestStreamSamples(t *testing.T) {
+	examples := []struct {
+		Parser StreamParser
+		In     string
+		Tag    string
+	}{
+		{Parser: StreamJson, In: "{\"id\": 1}\n{\"id\": 2, \"name\": \"b\"}\n", Tag: "json"},
+		{Parser: StreamYaml, In: "---\nid: 1\n---\nid: 2\nname: b\n...\n", Tag: "yaml"},
+	}
+
+	for i, ex := range examples {
+		m, err := InferStream(strings.NewReader(ex.In), ex.Parser, Options{Name: "Sample", Package: "gojson", Tags: []string{ex.Tag}, ConvertFloats: true})
+		if err != nil {
+			t.Fatalf("[Example %d] %s", i+1, err)
+		}
+		fields := m.Types[0].Type.Struct.Fields
+		if len(fields) != 2 {
+			t.Errorf("[Example %d] expected 2 fields, got %d", i+1, len(fields))
+			continue
+		}
+		if fields[0].Key != "id" || fields[0].Optional || fields[1].Key != "name" || !fields[1].Optional {
+			t.Errorf("[Example %d] unexpected fields %+v, %+v", i+1, fields[0], fields[1])
+		}
+	}
+}
+
+// TestStreamErrors tests that malformed streams are reported
+func TestStreamErrors(t *testing.T) {
+	for _, in := range []string{`{"a": 1`, `{"a": 1}}`, `[1, 2`, `"scalar"`, ``} {
+		if _, err := InferStream(strings.NewReader(in), StreamJson, Options{Name: "Sample"}); err == nil {
+			t.Errorf("expected an error streaming %q", in)
+		}
+	}
+}
+
+// TestLimits tests that limits cut inference short and are reported as warnings
+func TestLimits(t *testing.T) {
+	const in = `{"a": [{"x": 1}, {"y": 2}, {"z": 3}, {"w": 4}, {"v": 5}], "b": {"c": {"d": 1}}, "k": {"k1": 1, "k2": 2, "k3": 3}}`
+
+	examples := []struct {
+		Limits   Limits
+		Fields   int // fields of the elements of a
+		Warnings []string
+	}{
+		{Limits: Limits{}, Fields: 5},
+		{Limits: Limits{MaxElements: 2}, Fields: 2, Warnings: []string{"$.a: inspected 2 of 5 elements"}},
+		{Limits: Limits{Every: 2}, Fields: 3, Warnings: []string{"$.a: inspected 3 of 5 elements"}},
+		{Limits: Limits{Every: 2, MaxElements: 2}, Fields: 2, Warnings: []string{"$.a: inspected 2 of 5 elements"}},
+		{Limits: Limits{MaxElements: 3, Reservoir: true}, Fields: 3, Warnings: []string{"$.a: inspected 3 of 5 elements"}},
+		{Limits: Limits{MaxDepth: 2}, Fields: -1, Warnings: []string{"$.a[*]: not inspected beyond depth 2", "$.b.c: not inspected beyond depth 2"}},
+		{Limits: Limits{MaxKeys: 3}, Fields: 3, Warnings: []string{"$.a[*]: ignored keys beyond the first 3 (2 values)"}},
+	}
+
+	for i, ex := range examples {
+		for _, stream := range []bool{false, true} {
+			opts := Options{Name: "Limited", Package: "gojson", Limits: ex.Limits}
+			var m *Model
+			var err error
+			if stream {
+				m, err = InferStream(strings.NewReader(in), StreamJson, opts)
+			} else {
+				m, err = Infer(strings.NewReader(in), ParseJson, opts)
+			}
+			if err != nil {
+				t.Fatalf("[Example %d] %s", i+1, err)
+			}
+
+			a := m.Types[0].Type.Struct.Fields[0].Type.Elem
+			if ex.Fields < 0 {
+				if a.Name != "interface{}" {
+					t.Errorf("[Example %d] expected interface{} elements, got %s", i+1, a)
+				}
+			} else if a.Struct == nil || len(a.Struct.Fields) != ex.Fields {
+				t.Errorf("[Example %d] expected elements with %d fields, got %s", i+1, ex.Fields, a)
+			}
+			if strings.Join(m.Warnings, "\n") != strings.Join(ex.Warnings, "\n") {
+				t.Errorf("[Example %d] got warnings %q, but expected %q", i+1, m.Warnings, ex.Warnings)
+			}
+		}
+	}
+}
+
+// TestPath tests that types are inferred from the values selected by JSONPath expressions
+func TestPath(t *testing.T) {
+	const in = `{"data": {"items": [{"id": 1}, {"id": 2, "name": "b"}], "total": 2}, "meta": {"next": "x"}}`
+
+	examples := []struct {
+		Selections []Selection
+		Expected   string
+	}{
+		{
+			Selections: []Selection{{Name: "Item", Path: "$.data.items[*]"}},
+			Expected:   "package gojson\n\ntype Item struct {\n\tID   int64  `json:\"id\"`\n\tName string `json:\"name\"`\n}\n",
+		},
+		{
+			Selections: []Selection{{Name: "Second", Path: "$.data.items[1].name"}},
+			Expected:   "package gojson\n\ntype Second string\n",
+		},
+		{
+			Selections: []Selection{{Name: "Item", Path: `$["data"].items[0]`}, {Name: "Meta", Path: "$.*.next"}},
+			Expected:   "package gojson\n\ntype Meta string\n\ntype Item struct {\n\tID int64 `json:\"id\"`\n}\n",
+		},
+	}
+
+	for i, ex := range examples {
+		for _, stream := range []bool{false, true} {
+			opts := Options{Package: "gojson", Tags: []string{"json"}, ConvertFloats: true, Selections: ex.Selections}
+			var m *Model
+			var err error
+			if stream {
+				m, err = InferStream(strings.NewReader(in), StreamJson, opts)
+			} else {
+				m, err = Infer(strings.NewReader(in), ParseJson, opts)
+			}
+			if err != nil {
+				t.Fatalf("[Example %d] %s", i+1, err)
+			}
+			actual, err := m.Source()
+			if err != nil {
+				t.Fatalf("[Example %d] %s", i+1, err)
+			}
+			if string(actual) != ex.Expected {
+				t.Errorf("[Example %d] '%s' (expected) != '%s' (actual)", i+1, ex.Expected, actual)
+			}
+		}
+	}
+
+	for _, expr := range []string{"data", "$..id", "$.data.items[?(@.id)]", "$.none"} {
+		_, err := Infer(strings.NewReader(in), ParseJson, Options{Name: "Foo", Path: expr})
+		if err == nil {
+			t.Errorf("expected an error selecting %q", expr)
+		}
+	}
+}
+
+// TestStreamCsv tests that the columns of CSV and TSV rows are typed across all rows
+func TestStreamCsv(t *testing.T) {
+	const in = "id,name,price,active,created,notes\n1,Widget,2.5,true,2024-01-02,\n2,Gadget,3,false,2024-01-03T10:00:00Z,fragile\n"
+	const expected = "package gojson\n\nimport (\n\t\"time\"\n)\n\ntype Row struct {\n\tActive  bool      `csv:\"active\"`\n\tCreated time.Time `csv:\"created\"`\n\tID      int64     `csv:\"id\"`\n\tName    string    `csv:\"name\"`\n\tNotes   string    `csv:\"notes\"`\n\tPrice   float64   `csv:\"price\"`\n}\n"
+
+	examples := []struct {
+		Parser StreamParser
+		In     string
+	}{
+		{Parser: StreamCsv, In: in},
+		{Parser: StreamTsv, In: strings.Replace(in, ",", "\t", -1)},
+	}
+
+	for i, ex := range examples {
+		m, err := InferStream(strings.NewReader(ex.In), ex.Parser, Options{Name: "Row", Package: "gojson", Tags: []string{"csv"}})
+		if err != nil {
+			t.Fatalf("[Example %d] %s", i+1, err)
+		}
+		actual, err := m.Source()
+		if err != nil {
+			t.Fatalf("[Example %d] %s", i+1, err)
+		}
+		if string(actual) != expected {
+			t.Errorf("[Example %d] '%s' (expected) != '%s' (actual)", i+1, expected, actual)
+		}
+		for _, f := range m.Types[0].Type.Struct.Fields {
+			if f.Optional != (f.Key == "notes") {
+				t.Errorf("[Example %d] field %s should be optional only if it has empty cells", i+1, f.Key)
+			}
+		}
+	}
+
+	if _, err := InferStream(strings.NewReader("a,b\n1\n"), StreamCsv, Options{Name: "Row"}); err == nil {
+		t.Error("expected an error for a row with missing fields")
+	}
+}
+
+// TestGroupBy tests that every YAML document is sampled, into a type per discriminator value
+func TestGroupBy(t *testing.T) {
+	const in = "kind: Deployment\napiVersion: apps/v1\nreplicas: 2\n---\nkind: Service\napiVersion: v1\nports: [80]\n---\nkind: Deployment\napiVersion: apps/v1\nreplicas: 1\npaused: true\n---\nname: other\n"
+
+	examples := []struct {
+		GroupBy []string
+		Types   map[string]int // fields of each type
+	}{
+		{Types: map[string]int{"Manifest": 6}},
+		{GroupBy: []string{"kind"}, Types: map[string]int{"Deployment": 4, "Service": 3, "Manifest": 1}},
+		{GroupBy: []string{"kind", "apiVersion"}, Types: map[string]int{"DeploymentAppsV1": 4, "ServiceV1": 3, "Manifest": 1}},
+	}
+	yaml, _ := LookupFormat("yaml")
+
+	for i, ex := range examples {
+		for _, stream := range []bool{false, true} {
+			opts := Options{Name: "Manifest", Package: "gojson", Tags: []string{"yaml"}, GroupBy: ex.GroupBy}
+			var m *Model
+			var err error
+			if stream {
+				m, err = InferStream(strings.NewReader(in), StreamYaml, opts)
+			} else {
+				m, err = InferFormat(strings.NewReader(in), yaml, opts)
+			}
+			if err != nil {
+				t.Fatalf("[Example %d] %s", i+1, err)
+			}
+
+			if len(m.Types) != len(ex.Types) {
+				t.Errorf("[Example %d] expected %d types, got %d", i+1, len(ex.Types), len(m.Types))
+			}
+			for _, d := range m.Types {
+				if fields := len(d.Type.Struct.Fields); fields != ex.Types[d.Name] {
+					t.Errorf("[Example %d] expected %d fields in %s, got %d", i+1, ex.Types[d.Name], d.Name, fields)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateRequestType is the Content-Type of requests to Handler whose body
+// is a JSON object holding the input along with the options.
+const GenerateRequestType = "application/vnd.gojson+json"
+
+// generateRequest is the input and options of a request to Handler.
+type generateRequest struct {
+	Input     string   `json:"input"`
+	Name      string   `json:"name"`
+	Pkg       string   `json:"pkg"`
+	Tags      []string `json:"tags"`
+	Fmt       string   `json:"fmt"`
+	SubStruct bool     `json:"subStruct"`
+
+	Numbers NumberPolicy `json:"numbers"`
+}
+
+// An upload is an input of a request to Handler.
+type upload struct {
+	name string
+	data []byte
+}
+
+// errTooLarge reports a request larger than Handler accepts.
+var errTooLarge = errors.New("request body too large")
+
+// Handler returns an HTTP handler that generates Go types from the input
+// POSTed to it. The input is the body of the request, or the files of a
+// multipart/form-data request, each merged as another sample. Options are
+// given as query parameters, or as fields of a multipart form: name, pkg,
+// tags (comma separated), fmt, subStruct and numbers, defaulting as the flags of
+// the gojson command do. A request with Content-Type GenerateRequestType
+// instead has a JSON object body holding both, e.g.
+//
+//	{"input": "{\"id\": 1}", "name": "User", "tags": ["json"]}
+//
+// The generated source is returned as text/plain. Errors are returned as a
+// JSON object with the message under "error" and, where known, the "file",
+// "line", "column" and "path" of the offending input. Requests of more than
+// maxBytes bytes are refused. Handler is safe for concurrent use.
+func Handler(maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method must be POST"))
+			return
+		}
+
+		body := &limitedReader{r: r.Body, n: maxBytes + 1}
+		r.Body = ioutil.NopCloser(body)
+		req, uploads, err := readGenerateRequest(r, maxBytes)
+		switch {
+		case body.exceeded:
+			writeError(w, http.StatusRequestEntityTooLarge, errTooLarge)
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		src, err := generate(req, uploads)
+		switch err.(type) {
+		case nil:
+		case *InternalError:
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		default:
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(src)
+	})
+}
+
+// readGenerateRequest reads the input and options of a request to Handler.
+func readGenerateRequest(r *http.Request, maxBytes int64) (*generateRequest, []upload, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case GenerateRequestType:
+		var req generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, nil, errors.New("invalid request: " + err.Error())
+		}
+		return &req, []upload{{name: "input", data: []byte(req.Input)}}, nil
+
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxBytes); err != nil {
+			return nil, nil, err
+		}
+		req, err := queryRequest(r.Form)
+		if err != nil {
+			return nil, nil, err
+		}
+		var fields []string
+		for field := range r.MultipartForm.File {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		var uploads []upload
+		for _, field := range fields {
+			for _, fh := range r.MultipartForm.File[field] {
+				f, err := fh.Open()
+				if err != nil {
+					return nil, nil, err
+				}
+				data, err := ioutil.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return nil, nil, err
+				}
+				uploads = append(uploads, upload{name: fh.Filename, data: data})
+			}
+		}
+		if len(uploads) == 0 {
+			return nil, nil, errors.New("no files in multipart form")
+		}
+		return req, uploads, nil
+	}
+
+	req, err := queryRequest(r.URL.Query())
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, []upload{{name: "input", data: data}}, nil
+}
+
+// queryRequest reads the options of a request to Handler from query
+// parameters or form fields.
+func queryRequest(form url.Values) (*generateRequest, error) {
+	req := &generateRequest{
+		Name: form.Get("name"),
+		Pkg:  form.Get("pkg"),
+		Fmt:  form.Get("fmt"),
+	}
+	if tags := form.Get("tags"); tags != "" {
+		req.Tags = strings.Split(tags, ",")
+	}
+	if subStruct := form.Get("subStruct"); subStruct != "" {
+		b, err := strconv.ParseBool(subStruct)
+		if err != nil {
+			return nil, errors.New("subStruct must be true or false")
+		}
+		req.SubStruct = b
+	}
+	if numbers := form.Get("numbers"); numbers != "" {
+		if err := req.Numbers.UnmarshalText([]byte(numbers)); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// generate generates the Go source for the uploads of req.
+func generate(req *generateRequest, uploads []upload) ([]byte, error) {
+	if req.Fmt == "" {
+		req.Fmt = "json"
+	}
+	f, ok := LookupFormat(req.Fmt)
+	if !ok {
+		return nil, errors.New("fmt must be " + FormatNames())
+	}
+	opts := Options{
+		Name:          req.Name,
+		Package:       req.Pkg,
+		Tags:          req.Tags,
+		SubStruct:     req.SubStruct,
+		ConvertFloats: f.ConvertFloats,
+		Numbers:       req.Numbers,
+	}
+	if opts.Name == "" {
+		opts.Name = "Foo"
+	}
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+	if len(opts.Tags) == 0 {
+		opts.Tags = []string{f.Tag}
+	}
+
+	s, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range uploads {
+		if err := sampleInput(s, bytes.NewReader(u.data), f); err != nil {
+			return nil, withFile(err, u.name)
+		}
+	}
+	m, err := s.Model()
+	if err != nil {
+		return nil, err
+	}
+	return m.Source()
+}
+
+// writeError writes err as a JSON object, with its position if it has one.
+func writeError(w http.ResponseWriter, status int, err error) {
+	resp := struct {
+		Error  string `json:"error"`
+		File   string `json:"file,omitempty"`
+		Line   int    `json:"line,omitempty"`
+		Column int    `json:"column,omitempty"`
+		Path   string `json:"path,omitempty"`
+		Repro  string `json:"repro,omitempty"`
+	}{Error: err.Error()}
+	switch e := err.(type) {
+	case *SyntaxError:
+		resp.Error, resp.File, resp.Line, resp.Column = e.Reason, e.File, e.Line, e.Column
+	case *PathError:
+		resp.Error, resp.File, resp.Path = e.Reason, e.File, e.Path
+	case *InternalError:
+		resp.Path, resp.Repro = e.Path, e.Repro
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// limitedReader reads at most n bytes from r, and fails once it has read
+// all of them, remembering that it did.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if l.n <= 0 {
+		l.exceeded = true
+		return 0, errTooLarge
+	}
+	if int64(len(b)) > l.n {
+		b = b[:l.n]
+	}
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n <= 0 {
+		l.exceeded = true
+		return n, errTooLarge
+	}
+	return n, err
+}
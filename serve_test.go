@@ -0,0 +1,71 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandler tests that the HTTP API generates types from bodies, multipart files and JSON requests
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler(1 << 10))
+	defer server.Close()
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("name", "User")
+	mw.WriteField("fmt", "yaml")
+	fw, _ := mw.CreateFormFile("file", "a.yaml")
+	fw.Write([]byte("id: 1\n"))
+	fw, _ = mw.CreateFormFile("file", "b.yaml")
+	fw.Write([]byte("id: 2\nname: Bob\n"))
+	mw.Close()
+
+	examples := []struct {
+		Query       string
+		ContentType string
+		Body        string
+		Status      int
+		Out         string
+	}{
+		{Query: "?name=User&pkg=api&subStruct=true", ContentType: "application/json", Body: `{"id": 1}`, Status: 200,
+			Out: "package api\n\ntype User struct {\n\tID int64 `json:\"id\"`\n}\n"},
+		{Query: "?fmt=yaml&tags=json,yaml", Body: "id: 1\n", Status: 200,
+			Out: "package main\n\ntype Foo struct {\n\tID int `json:\"id\" yaml:\"id\"`\n}\n"},
+		{ContentType: mw.FormDataContentType(), Body: form.String(), Status: 200,
+			Out: "package main\n\ntype User struct {\n\tID   int    `yaml:\"id\"`\n\tName string `yaml:\"name\"`\n}\n"},
+		{ContentType: GenerateRequestType, Body: `{"input": "a = 1", "fmt": "toml", "name": "Config"}`, Status: 200,
+			Out: "package main\n\ntype Config struct {\n\tA int64 `toml:\"a\"`\n}\n"},
+		{Body: "{\n  \"id\": x}", Status: 422, Out: `{"error":"invalid character 'x' looking for beginning of value","file":"input","line":2,"column":9}` + "\n"},
+		{Query: "?fmt=ini", Body: "a=1", Status: 422, Out: `{"error":"fmt must be ` + FormatNames() + `"}` + "\n"},
+		{Body: strings.Repeat(" ", 1<<10) + "{}", Status: 413, Out: `{"error":"request body too large"}` + "\n"},
+	}
+
+	for i, ex := range examples {
+		resp, err := http.Post(server.URL+ex.Query, ex.ContentType, strings.NewReader(ex.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != ex.Status || string(out) != ex.Out {
+			t.Errorf("[Example %d] got %d '%s', but expected %d '%s'", i+1, resp.StatusCode, out, ex.Status, ex.Out)
+		}
+	}
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e struct{ Error string }
+	json.NewDecoder(resp.Body).Decode(&e)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || e.Error == "" {
+		t.Errorf("got %d %q for a GET request", resp.StatusCode, e.Error)
+	}
+}
@@ -0,0 +1,241 @@
+package gojson
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Session generates the types of a package from many named types, each
+// inferred from samples of its own. Sub-structs of identical shape are
+// declared once however many of the types use them, and those used by more
+// than one are named after the fields holding them, e.g. Owner, so that
+// their names do not depend on the order types are added in.
+//
+//	f, _ := gojson.LookupFormat("json")
+//	s := gojson.NewSession(gojson.Options{Package: "api", Tags: []string{"json"}})
+//	s.AddFiles("User", "", f, "samples/user.json")
+//	s.AddFiles("Repository", "$.items[*]", f, "samples/repos/")
+//	m, err := s.Model()
+type Session struct {
+	opts Options
+
+	mu    sync.Mutex
+	types map[string]*sessionType
+	added map[string]string // type each declared type was inferred for, by name
+}
+
+// A sessionType is a type of a Session, and the samples it is inferred from.
+type sessionType struct {
+	mu      sync.Mutex
+	path    string
+	s       *Sampler
+	sources []string
+}
+
+// NewSession returns a session generating the types of a package with
+// opts. Sub-structs are always named; Name, Path, Selections and Source
+// are given by each call to Add instead.
+func NewSession(opts Options) *Session {
+	opts.SubStruct = true
+	return &Session{opts: opts, types: make(map[string]*sessionType)}
+}
+
+// Add reads input in format f and merges it as another sample of the type
+// called name, or of the values of it that path selects, as Options.Path
+// does. Every input of a type must have the same path. source names the
+// input in errors and doc comments, e.g. its file name. Add is safe for
+// concurrent use, so that types can be inferred in parallel.
+func (s *Session) Add(name, path string, f Format, source string, input io.Reader) error {
+	t, err := s.lookup(name, path, f)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sources = append(t.sources, source)
+	if err := sampleInput(t.s, input, f); err != nil {
+		return withFile(err, source)
+	}
+	return nil
+}
+
+// AddFiles adds the files of InputFiles(input), for each of inputs, as
+// samples of the type called name, as Add does.
+func (s *Session) AddFiles(name, path string, f Format, inputs ...string) error {
+	t, err := s.lookup(name, path, f)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, input := range inputs {
+		files, err := InputFiles(input)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			in, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			err = sampleInput(t.s, in, f)
+			in.Close()
+			if err != nil {
+				return withFile(err, file)
+			}
+		}
+		t.sources = append(t.sources, input)
+	}
+	return nil
+}
+
+// lookup returns the type called name, creating it on first use.
+func (s *Session) lookup(name, path string, f Format) (*sessionType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.types[name]; ok {
+		if t.path != path {
+			return nil, fmt.Errorf("type %s: path %q differs from %q", name, path, t.path)
+		}
+		return t, nil
+	}
+
+	opts := s.opts
+	opts.Name, opts.Path, opts.Selections = name, path, nil
+	opts.ConvertFloats = f.ConvertFloats
+	sampler, err := NewSampler(opts)
+	if err != nil {
+		return nil, err
+	}
+	t := &sessionType{path: path, s: sampler}
+	s.types[name] = t
+	return t, nil
+}
+
+// Model returns the types inferred from the samples added so far, in order
+// of name, followed by the sub-structs they use.
+func (s *Session) Model() (*Model, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var roots []*root
+	s.added = make(map[string]string)
+	for _, name := range names {
+		t := s.types[name]
+		t.mu.Lock()
+		t.s.opts.Source = strings.Join(t.sources, ", ")
+		rs, err := t.s.modelRoots()
+		t.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			s.added[r.name] = name
+			if r.origin == "" {
+				r.origin = t.s.opts.Source
+				if r.sel != nil {
+					r.origin = r.sel.String() + " in " + r.origin
+				}
+			}
+		}
+		roots = append(roots, rs...)
+	}
+	return newModel(roots, s.opts)
+}
+
+// Split divides m into a model per output file, given the output file of
+// each of its types, so that a package can be written as several files. A
+// named struct goes to the output of the first type that uses it, and each
+// model imports the packages its types use. Types without an output, such
+// as that of NamedPlaceholder, go to the output of the first type.
+func (m *Model) Split(outputs map[string]string) map[string]*Model {
+	files := make(map[string]*Model)
+	file := func(output string) *Model {
+		f := files[output]
+		if f == nil {
+			f = &Model{Package: m.Package, Tags: m.Tags}
+			if len(files) == 0 {
+				f.Warnings, f.Suggestions, f.Unresolved = m.Warnings, m.Suggestions, m.Unresolved
+			}
+			files[output] = f
+		}
+		return f
+	}
+
+	placed := make(map[*Struct]bool)
+	var place func(f *Model, t *Type)
+	place = func(f *Model, t *Type) {
+		for ; t != nil; t = t.Elem {
+			if t.Key != nil {
+				place(f, t.Key)
+			}
+			for _, a := range t.Args {
+				place(f, a)
+			}
+			if path := importPath(t.Name); path != "" {
+				f.addImport(path)
+			}
+			if t.Struct == nil || placed[t.Struct] {
+				continue
+			}
+			if t.Struct.Name != "" {
+				placed[t.Struct] = true
+				f.Structs = append(f.Structs, t.Struct)
+			}
+			if t.Struct.Tuple {
+				f.addImport("encoding/json")
+				f.addImport("errors")
+			}
+			for _, field := range t.Struct.Fields {
+				place(f, field.Type)
+			}
+		}
+	}
+	for _, d := range m.Types {
+		output, ok := outputs[d.Name]
+		if !ok && len(m.Types) > 0 {
+			output = outputs[m.Types[0].Name]
+		}
+		f := file(output)
+		f.Types = append(f.Types, d)
+		place(f, d.Type)
+	}
+
+	// named structs follow the types in m, in the order of m.Structs
+	for _, f := range files {
+		sort.SliceStable(f.Structs, func(i, j int) bool {
+			return m.structIndex(f.Structs[i]) < m.structIndex(f.Structs[j])
+		})
+	}
+	return files
+}
+
+// addImport adds path to the imports of m, keeping them sorted.
+func (m *Model) addImport(path string) {
+	i := sort.SearchStrings(m.Imports, path)
+	if i < len(m.Imports) && m.Imports[i] == path {
+		return
+	}
+	m.Imports = append(m.Imports, "")
+	copy(m.Imports[i+1:], m.Imports[i:])
+	m.Imports[i] = path
+}
+
+// structIndex returns the index of s in m.Structs.
+func (m *Model) structIndex(s *Struct) int {
+	for i, st := range m.Structs {
+		if st == s {
+			return i
+		}
+	}
+	return len(m.Structs)
+}
@@ -0,0 +1,79 @@
+package gojson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sessionModel returns the model of a Session of the JSON files in examples/<dir>, as
+// types named after the files, added in the order of names or of the directory
+func sessionModel(t *testing.T, dir string, opts Options, names ...string) *Model {
+	if len(names) == 0 {
+		paths, err := filepath.Glob(filepath.Join("examples", dir, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			names = append(names, strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+	}
+
+	f, _ := LookupFormat("json")
+	s := NewSession(opts)
+	for _, name := range names {
+		input, err := os.Open(filepath.Join("examples", dir, name+".json"))
+		if err != nil {
+			t.Fatalf("error opening %s: %s", name, err)
+		}
+		err = s.Add(name, "", f, name+".json", input)
+		input.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := s.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// expectSource tests that the source of m matches examples/<golden>
+func expectSource(t *testing.T, m *Model, golden string) {
+	t.Helper()
+	expected, err := ioutil.ReadFile(filepath.Join("examples", golden))
+	if err != nil {
+		t.Fatalf("error reading %s: %s", golden, err)
+	}
+	actual, err := m.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sactual, sexpected := string(actual), string(expected); sactual != sexpected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", sexpected, sactual)
+	}
+}
+
+// TestSession tests that a session declares the sub-structs its types share once, with names that do not depend on the order the types are added in
+func TestSession(t *testing.T) {
+	for _, order := range [][]string{{"User", "Repository", "Issue"}, {"Issue", "Repository", "User"}} {
+		m := sessionModel(t, "shared", Options{Package: "api", Tags: []string{"json"}}, order...)
+		expectSource(t, m, "expected_shared.go.out")
+	}
+
+	input, err := ioutil.ReadFile(filepath.Join("examples", "shared", "User.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := LookupFormat("json")
+	s := NewSession(Options{Package: "api"})
+	if err := s.Add("User", "", f, "a.json", strings.NewReader(string(input))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("User", "$.owner", f, "b.json", strings.NewReader(string(input))); err == nil {
+		t.Error("expected an error for a type added with another path")
+	}
+}
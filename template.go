@@ -0,0 +1,51 @@
+package gojson
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs are the functions available to templates parsed with
+// ParseTemplateFile, in addition to the text/template builtins: comment
+// formats a doc as line comments, fieldName formats a key as a field
+// name, join, lower, quote and upper are those of the strings and strconv
+// packages, and tupleMethods renders the MarshalJSON and UnmarshalJSON
+// methods of a Struct whose Tuple is set.
+var TemplateFuncs = template.FuncMap{
+	"comment":      comment,
+	"fieldName":    FmtFieldName,
+	"join":         strings.Join,
+	"lower":        strings.ToLower,
+	"quote":        strconv.Quote,
+	"tupleMethods": tupleMethods,
+	"upper":        strings.ToUpper,
+}
+
+// ParseTemplateFile parses a text/template file that renders a Model.
+//
+// The template is named after path, so that parse and execution errors
+// report the file and line they occurred at.
+func ParseTemplateFile(path string) (*template.Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(path).Funcs(TemplateFuncs).Parse(string(b))
+}
+
+// ExecuteTemplate renders the model with tmpl. If the output is valid Go
+// source it is formatted with gofmt, otherwise it is returned as is.
+func (m *Model) ExecuteTemplate(tmpl *template.Template) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m); err != nil {
+		return nil, err
+	}
+	if formatted, err := format.Source(buf.Bytes()); err == nil {
+		return formatted, nil
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,145 @@
+package gojson
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func inferExample(t *testing.T, name string, opts Options) *Model {
+	f, err := os.Open(filepath.Join("examples", name))
+	if err != nil {
+		t.Fatalf("error opening %s: %s", name, err)
+	}
+	defer f.Close()
+
+	m, err := Infer(f, ParseJson, opts)
+	if err != nil {
+		t.Fatalf("error inferring %s: %s", name, err)
+	}
+	return m
+}
+
+// TestGoTemplate tests that templates/go.tmpl renders the same code as the built-in generator
+func TestGoTemplate(t *testing.T) {
+	tmpl, err := ParseTemplateFile(filepath.Join("templates", "go.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := make(map[string]*Model)
+	for _, name := range []string{"example.json", "example_array.json"} {
+		models[name] = inferExample(t, name, Options{Name: "User", Package: "gojson", Tags: []string{"json"}, SubStruct: true, ConvertFloats: true, Comments: true})
+	}
+	for _, tag := range []string{"json", "yaml"} {
+		models["bases "+tag] = sessionModel(t, "bases", Options{Package: "api", Tags: []string{tag}, Bases: Bases{Threshold: 0.5}})
+	}
+	models["envelopes"] = sessionModel(t, "envelopes", Options{Package: "api", Tags: []string{"json"}, GoVersion: "1.18", Comments: true})
+	tuples, err := Infer(strings.NewReader(`{"a": [[1, "a", true], [2, "b", false]]}`), ParseJson, Options{Name: "Chart", Package: "gojson", Tags: []string{"json"}, SubStruct: true, ConvertFloats: true, Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	models["tuples"] = tuples
+
+	for name, m := range models {
+		expected, err := m.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := m.ExecuteTemplate(tmpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(expected) {
+			t.Errorf("%s: '%s' (expected) != '%s' (actual)", name, expected, actual)
+		}
+	}
+}
+
+// typeCheck parses and type-checks the Go source of a package
+func typeCheck(src []byte) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "out.go", src, 0)
+	if err != nil {
+		return err
+	}
+	conf := types.Config{Importer: importer.Default()}
+	_, err = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	return err
+}
+
+// TestTemplates tests that every shipped template renders Go source that compiles
+func TestTemplates(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("templates", "*.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var models []*Model
+	for _, name := range []string{"example.json", "example_array.json"} {
+		models = append(models, inferExample(t, name, Options{Name: "User", Package: "gojson", SubStruct: true, ConvertFloats: true}))
+	}
+	models = append(models, sessionModel(t, "envelopes", Options{Package: "api", Tags: []string{"json"}, GoVersion: "1.18"}))
+	tuples, err := Infer(strings.NewReader(`{"a": [[1, "a", true], [2, "b", false]]}`), ParseJson, Options{Name: "Chart", Package: "gojson", Tags: []string{"json"}, ConvertFloats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	models = append(models, tuples)
+	for _, path := range paths {
+		tmpl, err := ParseTemplateFile(path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		for _, m := range models {
+			out, err := m.ExecuteTemplate(tmpl)
+			if err != nil {
+				t.Errorf("%s: %s", path, err)
+				continue
+			}
+			if err := typeCheck(out); err != nil {
+				t.Errorf("%s: invalid Go source: %s\n%s", path, err, out)
+			}
+			// top-level types that are not structs are declared too
+			for _, d := range m.Types {
+				if !strings.Contains(string(out), "type "+d.Name+" ") {
+					t.Errorf("%s: %s is not declared in '%s'", path, d.Name, out)
+				}
+			}
+		}
+	}
+}
+
+// TestTemplateErrorPosition tests that template errors name the file and line they occurred at
+func TestTemplateErrorPosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bad.tmpl")
+	if err := ioutil.WriteFile(path, []byte("package {{.Package}}\n\n{{.NoSuchField}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := ParseTemplateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := inferExample(t, "floats.json", Options{Name: "Stats", Package: "gojson"})
+	_, err = m.ExecuteTemplate(tmpl)
+	if err == nil {
+		t.Fatal("expected an error executing bad.tmpl")
+	}
+	if !strings.Contains(err.Error(), path+":3:") {
+		t.Errorf("error %q does not mention %s:3", err, path)
+	}
+}
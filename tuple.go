@@ -0,0 +1,129 @@
+package gojson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxTuple is the most elements of arrays that are taken for tuples.
+const maxTuple = 32
+
+// position records the element at index i of an array of shape s, which
+// is a scalar v or a nested value.
+func (s *shape) position(i int, v interface{}, scalar bool) {
+	if s.notTuple {
+		return
+	}
+	if !scalar || i >= maxTuple {
+		s.notTuple, s.positions = true, nil
+		return
+	}
+	for len(s.positions) <= i {
+		s.positions = append(s.positions, new(shape))
+	}
+	if v == nil {
+		s.positions[i].null()
+	} else {
+		s.positions[i].scalar(v)
+	}
+}
+
+// endTuple records the end of an array of shape s with n elements.
+func (s *shape) endTuple(n int) {
+	if s.notTuple {
+		return
+	}
+	s.arrays++
+	if s.arrays == 1 {
+		s.length = n
+	} else if n != s.length {
+		s.notTuple, s.positions = true, nil
+	}
+}
+
+// tupleFor returns the struct type of the arrays of shape s, if they are
+// tuples: arrays of scalars, in the same number in every array, whose
+// positions do not all have the same type, such as [date, open, high,
+// low, close] in market data, where the date is a string and the prices
+// are numbers. Positions that differ only as integers and floats make a
+// matrix of numbers instead. Every element becomes a field, and the struct
+// reads and writes the array form with MarshalJSON and UnmarshalJSON
+// methods, so tuples are only found for the json tag. At least two arrays
+// must have been seen. It returns nil for arrays that are not tuples.
+func (b *modelBuilder) tupleFor(s *shape, p path) *Type {
+	if !b.tuples || s.notTuple || s.arrays < 2 || s.length < 2 || s.elem == nil {
+		return nil
+	}
+	for _, pos := range s.positions {
+		if pos.kind != kindScalar || pos.nulls > 0 {
+			return nil
+		}
+	}
+	types := make([]*Type, len(s.positions))
+	kinds := make(map[string]bool)
+	for i, pos := range s.positions {
+		types[i] = b.typeFor(pos, p.elem())
+		if pos.numbers > 0 && pos.numbers == pos.seen {
+			// integers and floats widen to one number type
+			kinds["number"] = true
+		} else {
+			kinds[types[i].String()] = true
+		}
+	}
+	if len(kinds) == 1 {
+		// a list, such as [][]float64
+		return nil
+	}
+
+	st := &Struct{Tuple: true}
+	for i, pos := range s.positions {
+		field := &Field{
+			Name:   fmt.Sprintf("Field%d", i),
+			Type:   types[i],
+			Path:   p.elem().String(),
+			path:   p.elem(),
+			sample: pos.example(),
+		}
+		if b.opts.Comments {
+			field.Doc = fieldDoc(pos, s.arrays)
+		}
+		st.Fields = append(st.Fields, field)
+	}
+	b.imports["encoding/json"] = true
+	b.imports["errors"] = true
+
+	named := b.forceName(st)
+	if named == st && b.opts.Comments {
+		st.Doc = fmt.Sprintf("%s was inferred from the arrays at %s, by position.", st.Name, b.where(p))
+	}
+	return &Type{Struct: named}
+}
+
+// tupleMethods returns the MarshalJSON and UnmarshalJSON methods of the
+// tuple struct s, which read and write it as an array.
+func tupleMethods(s *Struct) string {
+	var reads, values []string
+	for i, f := range s.Fields {
+		reads = append(reads, fmt.Sprintf("if err := json.Unmarshal(a[%d], &t.%s); err != nil {\nreturn err\n}", i, f.Name))
+		values = append(values, "t."+f.Name)
+	}
+	return fmt.Sprintf(`
+
+// UnmarshalJSON reads a %[1]s from an array of its fields, in order.
+func (t *%[1]s) UnmarshalJSON(data []byte) error {
+	var a []json.RawMessage
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	if len(a) != %[2]d {
+		return errors.New("%[1]s: expected an array of %[2]d elements")
+	}
+	%[3]s
+	return nil
+}
+
+// MarshalJSON writes t as an array of its fields, in order.
+func (t %[1]s) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{%[4]s})
+}`, s.Name, len(s.Fields), strings.Join(reads, "\n"), strings.Join(values, ", "))
+}
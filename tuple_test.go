@@ -0,0 +1,60 @@
+package gojson
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTuples tests that arrays of scalars whose positions have different types, in the same order and number, become structs read from and written as arrays
+func TestTuples(t *testing.T) {
+	generate := func(input string) string {
+		m, err := Infer(strings.NewReader(input), ParseJson, Options{Name: "Chart", Package: "main", Tags: []string{"json"}, ConvertFloats: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := m.Source()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(src)
+	}
+
+	src := generate(`{"candles": [["2021-01-01", 1.5, true], ["2021-01-02", 2.5, false]], "tags": [1, 2]}`)
+	expected := "package main\n\nimport (\n\t\"encoding/json\"\n\t\"errors\"\n)\n\ntype Chart struct {\n\tCandles []Chart_sub1 `json:\"candles\"`\n\tTags    []int64      `json:\"tags\"`\n}\n\ntype Chart_sub1 struct {\n\tField0 string\n\tField1 float64\n\tField2 bool\n}\n\n// UnmarshalJSON reads a Chart_sub1 from an array of its fields, in order.\nfunc (t *Chart_sub1) UnmarshalJSON(data []byte) error {\n\tvar a []json.RawMessage\n\tif err := json.Unmarshal(data, &a); err != nil {\n\t\treturn err\n\t}\n\tif len(a) != 3 {\n\t\treturn errors.New(\"Chart_sub1: expected an array of 3 elements\")\n\t}\n\tif err := json.Unmarshal(a[0], &t.Field0); err != nil {\n\t\treturn err\n\t}\n\tif err := json.Unmarshal(a[1], &t.Field1); err != nil {\n\t\treturn err\n\t}\n\tif err := json.Unmarshal(a[2], &t.Field2); err != nil {\n\t\treturn err\n\t}\n\treturn nil\n}\n\n// MarshalJSON writes t as an array of its fields, in order.\nfunc (t Chart_sub1) MarshalJSON() ([]byte, error) {\n\treturn json.Marshal([]interface{}{t.Field0, t.Field1, t.Field2})\n}\n"
+	if src != expected {
+		t.Errorf("'%s' (expected) != '%s' (actual)", expected, src)
+	}
+
+	for _, input := range []string{
+		`{"candles": [["2021-01-01", 1.5, true]]}`,                      // a single array
+		`{"candles": [["2021-01-01", 1.5, true], ["2021-01-02", 2.5]]}`, // of different lengths
+		`{"candles": [["2021-01-01", 1.5], ["2021-01-02", {"a": 1}]]}`,  // not only scalars
+		`{"candles": [["2021-01-01", 1.5], [1.5, "2021-01-02"]]}`,       // in another order
+	} {
+		if src := generate(input); !strings.Contains(src, "[]interface{}") {
+			t.Errorf("%s: expected no tuple, got '%s'", input, src)
+		}
+	}
+
+	src = generate(`{"candles": [["a", 1609459200000, 1.5], ["b", 1609545600000, 2]]}`)
+	if !strings.Contains(src, "\tField0 string\n\tField1 int64\n\tField2 float64\n") {
+		t.Errorf("expected a tuple of a string, an int64 and a float64, got '%s'", src)
+	}
+	for _, input := range []string{
+		`{"points": [[1.5, 2.5], [3, 4.5]]}`,
+		`{"points": [[1, 2], [3, 4.5]]}`, // integers and floats
+		`{"candles": [[1609459200000, 1.5, 2.5, 1.25, 2], [1609545600000, 2, 3.5, 1.5, 3.25]]}`,
+	} {
+		if src := generate(input); !strings.Contains(src, "[][]float64") {
+			t.Errorf("%s: expected arrays of numbers to stay lists, got '%s'", input, src)
+		}
+	}
+
+	m, err := InferStream(strings.NewReader(`[1, "a"] [2, "b"]`), StreamJson, Options{Name: "Chart", Package: "main", Tags: []string{"json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src, err := m.Source(); err != nil || !strings.Contains(string(src), "type Chart = Chart_sub1\n") {
+		t.Errorf("expected a top-level tuple to be an alias, got '%s' (%v)", src, err)
+	}
+}
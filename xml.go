@@ -0,0 +1,189 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Keys of the objects decoded by ParseXml are the tags of the xml fields
+// they are unmarshalled into, as understood by encoding/xml.
+const (
+	xmlNameKey     = "XMLName"   // name of the root element
+	xmlCharDataKey = ",chardata" // text of an element with attributes or children
+	xmlAttrSuffix  = ",attr"
+)
+
+// An xmlNode is an element of an XML document.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     bytes.Buffer
+	children []*xmlNode
+}
+
+// xmlInfo records how the elements at one path of a document look.
+type xmlInfo struct {
+	repeated bool // more than once within the same parent
+	complex  bool // with attributes or children
+	text     bool // with non-blank text
+}
+
+// ParseXml parses an XML document. Elements become objects keyed by xml
+// struct tags: attributes are keyed "name,attr", the text of elements with
+// attributes or children ",chardata", and child elements by their name,
+// qualified by their namespace if it differs from their parent's. Elements
+// that repeat within a parent anywhere in the document become arrays
+// everywhere, elements that only ever hold text become strings, and the
+// name of the root element is stored under "XMLName".
+func ParseXml(input io.Reader) (interface{}, error) {
+	root, err := readXml(input)
+	if err != nil {
+		return nil, err
+	}
+
+	info := make(map[string]*xmlInfo)
+	analyzeXml(root, "", info)
+	info[""].complex = true
+
+	obj := convertXml(root, "", info).(map[string]interface{})
+	obj[xmlNameKey] = xmlKey(root.name, "")
+	return obj, nil
+}
+
+// readXml reads the tree of elements of an XML document.
+func readXml(input io.Reader) (*xmlNode, error) {
+	d := xml.NewDecoder(input)
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xmlError(err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: tok.Name, attrs: tok.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		}
+	}
+	if root == nil {
+		return nil, &SyntaxError{Reason: "no root element"}
+	}
+	return root, nil
+}
+
+// analyzeXml records in info how n, found at path p, and its descendants
+// look.
+func analyzeXml(n *xmlNode, p string, info map[string]*xmlInfo) {
+	i := info[p]
+	if i == nil {
+		i = new(xmlInfo)
+		info[p] = i
+	}
+	if len(xmlAttrs(n)) > 0 || len(n.children) > 0 {
+		i.complex = true
+	}
+	if strings.TrimSpace(n.text.String()) != "" {
+		i.text = true
+	}
+
+	counts := make(map[string]int)
+	for _, c := range n.children {
+		cp := p + "/" + xmlKey(c.name, n.name.Space)
+		counts[cp]++
+		analyzeXml(c, cp, info)
+	}
+	for cp, count := range counts {
+		if count > 1 {
+			info[cp].repeated = true
+		}
+	}
+}
+
+// convertXml converts n, found at path p, into the value it is
+// unmarshalled from.
+func convertXml(n *xmlNode, p string, info map[string]*xmlInfo) interface{} {
+	if !info[p].complex {
+		return strings.TrimSpace(n.text.String())
+	}
+
+	obj := make(map[string]interface{})
+	for _, a := range xmlAttrs(n) {
+		obj[xmlKey(a.Name, "")+xmlAttrSuffix] = a.Value
+	}
+	if info[p].text {
+		obj[xmlCharDataKey] = strings.TrimSpace(n.text.String())
+	}
+	for _, c := range n.children {
+		key := xmlKey(c.name, n.name.Space)
+		cp := p + "/" + key
+		v := convertXml(c, cp, info)
+		if !info[cp].repeated {
+			obj[key] = v
+			continue
+		}
+		elems, _ := obj[key].([]interface{})
+		obj[key] = append(elems, v)
+	}
+	return obj
+}
+
+// xmlAttrs returns the attributes of n, without namespace declarations.
+func xmlAttrs(n *xmlNode) []xml.Attr {
+	attrs := make([]xml.Attr, 0, len(n.attrs))
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+// xmlKey returns the xml tag name of an element or attribute within a
+// parent in namespace space.
+func xmlKey(name xml.Name, space string) string {
+	if name.Space == "" || name.Space == space {
+		return name.Local
+	}
+	return name.Space + " " + name.Local
+}
+
+// xmlFieldName returns the Go field name for key, an xml tag name.
+func xmlFieldName(key string) string {
+	switch key {
+	case xmlNameKey:
+		return key
+	case xmlCharDataKey:
+		return "CharData"
+	}
+	return FmtFieldName(xmlLocalName(key))
+}
+
+// xmlLocalName returns key, an xml tag name, without its namespace and
+// options.
+func xmlLocalName(key string) string {
+	key = strings.TrimSuffix(key, xmlAttrSuffix)
+	if i := strings.LastIndex(key, " "); i >= 0 {
+		key = key[i+1:]
+	}
+	return key
+}
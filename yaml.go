@@ -0,0 +1,226 @@
+package gojson
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A keyedMap is a map whose keys are not strings, such as a YAML mapping
+// with integer keys. Its keys are formatted with formatKey.
+type keyedMap struct {
+	key    string // name of the Go type of the keys, e.g. "int"
+	values map[string]interface{}
+	keys   map[string]interface{} // the keys as decoded, if known
+}
+
+// sortedKeys returns the keys of m in order.
+func (m keyedMap) sortedKeys() []string {
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readYaml calls sample with each document of a stream of YAML documents.
+func readYaml(input io.Reader, sample func(interface{})) error {
+	r := &errReader{r: input}
+	dec := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err == io.EOF {
+			return nil
+		} else if r.err != nil {
+			return r.err
+		} else if err != nil {
+			return yamlError(err)
+		}
+
+		y := &yamlConverter{values: make(map[*yaml.Node]interface{})}
+		v, err := y.value(&doc)
+		if err != nil {
+			return err
+		}
+		// empty documents, e.g. before a leading "---", are not samples
+		if v != nil {
+			sample(v)
+		}
+	}
+}
+
+// yamlConverter converts YAML nodes into the values they describe,
+// resolving aliases and merge keys.
+type yamlConverter struct {
+	values map[*yaml.Node]interface{} // converted anchored nodes
+}
+
+func (y *yamlConverter) value(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return y.value(n.Content[0])
+	case yaml.AliasNode:
+		return y.alias(n)
+	case yaml.SequenceNode:
+		arr := make([]interface{}, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := y.value(c)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case yaml.MappingNode:
+		return y.mapping(n)
+	}
+	return y.scalar(n)
+}
+
+// alias returns the value of the node an alias refers to, converting it
+// only once so that every alias to it shares its value.
+func (y *yamlConverter) alias(n *yaml.Node) (interface{}, error) {
+	if v, ok := y.values[n.Alias]; ok {
+		if v == y {
+			return nil, &SyntaxError{Line: n.Line, Column: n.Column, Reason: fmt.Sprintf("anchor %q contains itself", n.Value)}
+		}
+		return v, nil
+	}
+	y.values[n.Alias] = y // in progress
+	v, err := y.value(n.Alias)
+	if err != nil {
+		return nil, err
+	}
+	y.values[n.Alias] = v
+	return v, nil
+}
+
+func (y *yamlConverter) scalar(n *yaml.Node) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!binary":
+		// binary values may be broken across lines
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.Value), ""))
+		if err != nil {
+			return nil, &SyntaxError{Line: n.Line, Column: n.Column, Reason: "!!binary value contains invalid base64 data"}
+		}
+		return b, nil
+	case "!!str":
+		return n.Value, nil
+	}
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil, yamlError(err)
+	}
+	return v, nil
+}
+
+// mapping converts a mapping into an object if all its keys are strings,
+// and into a keyedMap otherwise. Merge keys ("<<") merge the entries of
+// other mappings that the mapping does not have itself.
+func (y *yamlConverter) mapping(n *yaml.Node) (interface{}, error) {
+	keys := make([]interface{}, 0, len(n.Content)/2)
+	values := make([]interface{}, 0, len(n.Content)/2)
+	var merged []interface{}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Kind != yaml.ScalarNode {
+			return nil, &SyntaxError{Line: k.Line, Column: k.Column, Reason: "mapping keys must be scalars"}
+		}
+		value, err := y.value(v)
+		if err != nil {
+			return nil, err
+		}
+		if k.ShortTag() == "!!merge" {
+			if elems, ok := value.([]interface{}); ok {
+				merged = append(merged, elems...)
+			} else {
+				merged = append(merged, value)
+			}
+			continue
+		}
+		key, err := y.scalar(k)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	for _, m := range merged {
+		switch m := m.(type) {
+		case map[string]interface{}:
+			for key, value := range m {
+				keys = append(keys, key)
+				values = append(values, value)
+			}
+		case keyedMap:
+			for _, key := range m.sortedKeys() {
+				keys = append(keys, key)
+				values = append(values, m.values[key])
+			}
+		default:
+			return nil, &SyntaxError{Line: n.Line, Column: n.Column, Reason: "map merge requires maps as values"}
+		}
+	}
+
+	keyType := ""
+	for _, key := range keys {
+		if t := typeName(key); keyType == "" {
+			keyType = t
+		} else if t != keyType {
+			keyType = "interface{}"
+		}
+	}
+
+	if keyType == "" || keyType == "string" {
+		obj := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			// earlier entries take precedence over merged ones
+			if _, ok := obj[key.(string)]; !ok {
+				obj[key.(string)] = values[i]
+			}
+		}
+		return obj, nil
+	}
+	m := keyedMap{key: keyType, values: make(map[string]interface{}, len(keys)), keys: make(map[string]interface{}, len(keys))}
+	for i, key := range keys {
+		if _, ok := m.values[formatKey(key)]; !ok {
+			m.values[formatKey(key)] = values[i]
+			m.keys[formatKey(key)] = key
+		}
+	}
+	return m, nil
+}
+
+// formatKey formats a map key as a string.
+func formatKey(key interface{}) string {
+	if t, ok := key.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(key)
+}
+
+// An errReader remembers the error its reader failed with, which the YAML
+// decoder only reports as text.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,623 @@
+package gojson
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A yamlStream reports a stream of YAML documents to a Sampler as it reads
+// them. yaml.v3 only decodes whole documents, so yamlStream follows the
+// block structure of each document line by line, reporting its block
+// mappings and sequences as events, and only decodes their innermost
+// entries, such as "key: value" or "- [1, 2]", with yaml.v3. Memory use is
+// bounded by the largest such entry rather than by the document.
+//
+// Documents whose root is not a block collection, such as a flow mapping,
+// are decoded whole.
+type yamlStream struct {
+	r    *bufio.Reader
+	s    *Sampler
+	line int       // number of the last line read
+	next *yamlLine // line read ahead, if any
+	err  error     // error reading the input, once it is exhausted
+
+	directives []string // directives of the current document
+
+	// like yaml.v3, anchors may be referred to in later documents
+	y       *yamlConverter
+	anchors map[string]*yaml.Node // anchored nodes, by name
+	order   []string              // names of anchors, in the order they were defined
+}
+
+// A yamlLine is a line of a YAML stream without its line break.
+type yamlLine struct {
+	text   string
+	num    int
+	indent int    // number of spaces before the content
+	body   string // content, without trailing white space
+}
+
+func newYamlLine(text string, num int) *yamlLine {
+	body := strings.TrimLeft(text, " ")
+	indent := len(text) - len(body)
+	return &yamlLine{text: text, num: num, indent: indent, body: strings.TrimRight(body, " \t")}
+}
+
+// blank reports whether l holds nothing but white space or a comment.
+func (l *yamlLine) blank() bool {
+	return l.body == "" || l.body[0] == '#'
+}
+
+// marker reports whether l starts ("---") or ends ("...") a document.
+func (l *yamlLine) marker() bool {
+	return l.indent == 0 && (yamlIndicator(l.body, "---") || yamlIndicator(l.body, "..."))
+}
+
+// item reports whether l is an entry of a block sequence.
+func (l *yamlLine) item() bool {
+	return yamlIndicator(l.body, "-")
+}
+
+// entry reports whether l starts an entry of a block mapping.
+func (l *yamlLine) entry() bool {
+	return yamlIndicator(l.body, "?") || yamlKeyEnd(l.body) >= 0
+}
+
+// yamlIndicator reports whether s starts with the indicator ind followed by
+// white space or nothing.
+func yamlIndicator(s, ind string) bool {
+	return strings.HasPrefix(s, ind) && (len(s) == len(ind) || s[len(ind)] == ' ' || s[len(ind)] == '\t')
+}
+
+// yamlKeyEnd returns the index of the colon that ends the implicit key at
+// the start of s, or -1 if s does not start with a key.
+func yamlKeyEnd(s string) int {
+	i := 0
+	switch {
+	case s == "":
+		return -1
+	case s[0] == '"' || s[0] == '\'':
+		q := s[0]
+		for i = 1; i < len(s); i++ {
+			if q == '"' && s[i] == '\\' {
+				i++
+			} else if s[i] == q {
+				if q == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+		}
+		if i >= len(s) {
+			return -1
+		}
+		i++
+	case s[0] == '[' || s[0] == '{':
+		depth := 0
+		for ; i < len(s); i++ {
+			if c := s[i]; c == '[' || c == '{' {
+				depth++
+			} else if c == ']' || c == '}' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		if i >= len(s) {
+			return -1
+		}
+		i++
+	case strings.IndexByte(",]}#|>@`%", s[0]) >= 0, yamlIndicator(s, "-"), yamlIndicator(s, "?"), yamlIndicator(s, ":"):
+		// indicators that cannot start a plain key; "-1: a" is a key
+		return -1
+	}
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ':':
+			if i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\t' {
+				return i
+			}
+		case '#':
+			if i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// StreamYaml reads a stream of YAML documents, reporting each of them as a
+// sample. The block mappings and sequences of a document are reported as
+// they are read, so memory use is bounded by their largest entry rather
+// than by the document.
+func StreamYaml(input io.Reader, s *Sampler) error {
+	st := &yamlStream{
+		r:       bufio.NewReader(input),
+		s:       s,
+		y:       &yamlConverter{values: make(map[*yaml.Node]interface{})},
+		anchors: make(map[string]*yaml.Node),
+	}
+	for {
+		l := st.content()
+		if l == nil {
+			return st.err
+		}
+		if l.indent == 0 && l.body[0] == '%' {
+			// directives, such as "%TAG", apply to the document that follows
+			st.directives = append(st.directives, st.take().text)
+			if err := yaml.Unmarshal([]byte(strings.Join(st.directives, "\n")+"\n---\n"), new(yaml.Node)); err != nil {
+				return yamlError(err)
+			}
+			continue
+		}
+		if l.marker() {
+			st.take()
+			if l.body[0] == '.' {
+				continue
+			}
+			// content after "---" starts the root node on the same line
+			if rest := strings.TrimLeft(l.body[3:], " \t"); rest != "" && rest[0] != '#' {
+				if err := st.document(l); err != nil {
+					return err
+				}
+				st.directives = nil
+				continue
+			}
+			if l = st.content(); l == nil || l.marker() {
+				st.directives = nil
+				continue
+			}
+		}
+
+		var err error
+		reason := "did not find expected key"
+		switch {
+		case l.item():
+			err = st.sequence(l.indent)
+			reason = "did not find expected '-' indicator"
+		case l.entry():
+			err = st.mapping(l.indent)
+		default:
+			st.take()
+			err = st.document(l)
+		}
+		if err != nil {
+			return err
+		}
+		if l := st.content(); l != nil && !l.marker() {
+			return &SyntaxError{Line: l.num, Reason: reason}
+		}
+		st.directives = nil
+	}
+}
+
+// peek returns the next line without reading it, or nil at the end of the
+// input.
+func (st *yamlStream) peek() *yamlLine {
+	if st.next != nil || st.err != nil {
+		return st.next
+	}
+	text, err := st.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		st.err = err
+		return nil
+	}
+	if text == "" && err == io.EOF {
+		return nil
+	}
+	st.line++
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+	if st.line == 1 {
+		text = strings.TrimPrefix(text, "\ufeff")
+	}
+	st.next = newYamlLine(text, st.line)
+	return st.next
+}
+
+// take reads the next line.
+func (st *yamlStream) take() *yamlLine {
+	l := st.peek()
+	st.next = nil
+	return l
+}
+
+// content skips blank lines and comments, and returns the next line
+// without reading it.
+func (st *yamlStream) content() *yamlLine {
+	for {
+		l := st.peek()
+		if l == nil || !l.blank() {
+			return l
+		}
+		st.take()
+	}
+}
+
+// within reports whether l is part of a node indented by more than indent.
+func within(l *yamlLine, indent int) bool {
+	return l != nil && !l.marker() && (l.blank() || l.indent > indent)
+}
+
+// compact reports whether l is an entry of a sequence that is the value of
+// a mapping entry at the same indent.
+func compact(l *yamlLine, indent int) bool {
+	return l != nil && !l.marker() && l.indent == indent && l.item()
+}
+
+// skip reads the value of a mapping entry at indent without reporting it.
+func (st *yamlStream) skip(indent int) {
+	for next := st.peek(); within(next, indent) || compact(next, indent); next = st.peek() {
+		st.take()
+	}
+}
+
+// document decodes the rest of the document starting with first whole.
+func (st *yamlStream) document(first *yamlLine) error {
+	lines := []string{first.text}
+	for l := st.peek(); l != nil && !l.marker(); l = st.peek() {
+		lines = append(lines, st.take().text)
+	}
+	n, err := st.decode(lines, first.num, "")
+	if err != nil || n == nil {
+		return err
+	}
+	v, err := st.y.value(n)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		st.s.Value(v)
+	}
+	return nil
+}
+
+// sequence reports the block sequence whose entries start at column indent.
+func (st *yamlStream) sequence(indent int) error {
+	st.s.BeginArray()
+	for l := st.content(); l != nil && !l.marker() && l.indent == indent && l.item(); l = st.content() {
+		st.take()
+		if err := st.item(l); err != nil {
+			return err
+		}
+	}
+	st.s.EndArray()
+	return nil
+}
+
+// item reports the entry of a block sequence starting with line l.
+func (st *yamlStream) item(l *yamlLine) error {
+	rest := strings.TrimLeft(l.body[1:], " \t")
+	if rest == "" || rest[0] == '#' {
+		// the entry is on the lines that follow, if any
+		next := st.content()
+		switch {
+		case next == nil || next.marker() || next.indent <= l.indent:
+			st.s.Null()
+			return nil
+		case next.item():
+			return st.sequence(next.indent)
+		case next.entry():
+			return st.mapping(next.indent)
+		}
+	} else if inner := newYamlLine(l.text[:l.indent]+" "+l.text[l.indent+1:], l.num); inner.item() || inner.entry() && !strings.ContainsAny(rest[:1], "&!*") {
+		// a compact collection such as "- key: value", whose entries are
+		// indented like its first one
+		st.next = inner
+		if inner.item() {
+			return st.sequence(inner.indent)
+		}
+		return st.mapping(inner.indent)
+	}
+
+	v, err := st.leaf(l)
+	if err != nil {
+		return err
+	}
+	st.s.Value(v)
+	return nil
+}
+
+// mapping reports the block mapping whose keys start at column indent.
+// The mapping is an object if its first key is a string, and a map
+// otherwise. Merge keys ("<<") are reported last, for the keys the
+// mapping does not have itself.
+func (st *yamlStream) mapping(indent int) error {
+	seen := make(map[string]bool)
+	var merged []interface{}
+	begun, object := false, true
+
+	for {
+		l := st.content()
+		if l == nil || l.marker() || l.indent != indent || l.item() {
+			break
+		}
+		st.take()
+		if !l.entry() {
+			return &SyntaxError{Line: l.num, Reason: "could not find expected ':'"}
+		}
+
+		k, v, err := st.entry(l)
+		if err != nil {
+			return err
+		}
+		if k.Kind != yaml.ScalarNode {
+			return &SyntaxError{Line: k.Line + l.num - 1, Column: k.Column, Reason: "mapping keys must be scalars"}
+		}
+		if k.ShortTag() == "!!merge" {
+			if elems, ok := v.([]interface{}); ok {
+				merged = append(merged, elems...)
+			} else {
+				merged = append(merged, v)
+			}
+			continue
+		}
+
+		key, err := st.y.scalar(k)
+		if err != nil {
+			return err
+		}
+		if !begun {
+			begun = true
+			if t := typeName(key); t != "string" {
+				object = false
+				st.s.BeginMap(t)
+			} else {
+				st.s.BeginObject()
+			}
+		}
+		name := formatKey(key)
+		if seen[name] {
+			// like earlier entries, the first of duplicate keys is kept
+			if v == st {
+				st.skip(indent)
+			}
+			continue
+		}
+		seen[name] = true
+		st.s.Key(name)
+		if v == st {
+			// the value is a block collection on the lines that follow
+			next := st.content()
+			if next.item() {
+				err = st.sequence(next.indent)
+			} else {
+				err = st.mapping(next.indent)
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			st.s.Value(v)
+		}
+	}
+
+	if !begun {
+		st.s.BeginObject()
+	}
+	for _, m := range merged {
+		switch m := m.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(m))
+			for key := range m {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if !seen[key] {
+					seen[key] = true
+					st.s.Key(key)
+					st.s.Value(m[key])
+				}
+			}
+		case keyedMap:
+			for _, key := range m.sortedKeys() {
+				if !seen[key] {
+					seen[key] = true
+					st.s.Key(key)
+					st.s.Value(m.values[key])
+				}
+			}
+		default:
+			return &SyntaxError{Line: st.line, Reason: "map merge requires maps as values"}
+		}
+	}
+	if object {
+		st.s.EndObject()
+	} else {
+		st.s.EndMap()
+	}
+	return nil
+}
+
+// entry decodes the entry of a block mapping starting with line l. If its
+// value is a block collection on the lines that follow, only the key is
+// decoded, and the value returned is st.
+func (st *yamlStream) entry(l *yamlLine) (*yaml.Node, interface{}, error) {
+	if end := yamlKeyEnd(l.body); end >= 0 {
+		rest := strings.TrimLeft(l.body[end+1:], " \t")
+		key := strings.TrimSpace(l.body[:end])
+		if (rest == "" || rest[0] == '#') && key != "<<" {
+			next := st.content()
+			if next != nil && !next.marker() && (next.indent > l.indent && (next.item() || next.entry()) || compact(next, l.indent)) {
+				if k := plainYaml(key, l.num); k != nil {
+					return k, st, nil
+				}
+				n, err := st.decode([]string{l.text}, l.num, "")
+				if err != nil {
+					return nil, nil, err
+				}
+				return n.Content[0].Content[0], st, nil
+			}
+		}
+	}
+
+	lines, single := st.lines(l, func(next *yamlLine) bool {
+		return within(next, l.indent) || compact(next, l.indent) || next != nil && next.indent == l.indent && yamlIndicator(next.body, ":")
+	})
+	if end := yamlKeyEnd(l.body); single && end >= 0 {
+		// most entries are as simple as "name: value", and need no parsing
+		if k, v := plainYaml(strings.TrimSpace(l.body[:end]), l.num), plainYaml(strings.TrimSpace(l.body[end+1:]), l.num); k != nil && v != nil {
+			value, err := st.y.value(v)
+			return k, value, err
+		}
+	}
+	n, err := st.decode(lines, l.num, "\"\\0\":")
+	if err != nil {
+		return nil, nil, err
+	}
+	if n == nil || n.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, &SyntaxError{Line: l.num, Reason: "could not find expected ':'"}
+	}
+	entries := n.Content[0].Content
+	k := entries[len(entries)-2]
+	v, err := st.y.value(entries[len(entries)-1])
+	return k, v, err
+}
+
+// lines reads the lines of a node starting with line l, for as long as
+// more reports that the next line is part of it. It also reports whether
+// all but the first line are blank.
+func (st *yamlStream) lines(l *yamlLine, more func(*yamlLine) bool) ([]string, bool) {
+	lines, single := []string{l.text}, true
+	for next := st.peek(); more(next); next = st.peek() {
+		single = single && next.blank()
+		lines = append(lines, st.take().text)
+	}
+	return lines, single
+}
+
+// plainYaml returns a node for the scalar s on line num if it is empty or
+// plain, made of ASCII letters, digits, spaces and the punctuation of
+// numbers and names, so that it needs no parsing. Otherwise it returns nil.
+func plainYaml(s string, num int) *yaml.Node {
+	if s == "" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: num}
+	}
+	if s[0] == '-' && (len(s) == 1 || !('0' <= s[1] && s[1] <= '9' || s[1] == '.')) {
+		// "-" and "- x" are sequences
+		return nil
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte(" _-+./", c) >= 0) {
+			return nil
+		}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: s, Line: num}
+}
+
+// leaf decodes the entry of a block sequence starting with line l.
+func (st *yamlStream) leaf(l *yamlLine) (interface{}, error) {
+	lines, single := st.lines(l, func(next *yamlLine) bool { return within(next, l.indent) })
+	if v := plainYaml(strings.TrimSpace(l.body[1:]), l.num); single && v != nil {
+		return st.y.value(v)
+	}
+	n, err := st.decode(lines, l.num, "-")
+	if err != nil {
+		return nil, err
+	}
+	items := n.Content[0].Content
+	return st.y.value(items[len(items)-1])
+}
+
+// decode decodes the lines of a node starting at line num, and records its
+// anchors. If the node refers to anchors of earlier nodes, they are
+// decoded again ahead of it, as an entry introduced by prefix, at the
+// indentation of the first line.
+func (st *yamlStream) decode(lines []string, num int, prefix string) (*yaml.Node, error) {
+	// yaml.v3 leaves out the line of errors on the first line, so the node
+	// starts on the second one, after the directives of the document
+	head := "\n"
+	if len(st.directives) > 0 {
+		head = strings.Join(st.directives, "\n") + "\n"
+		if !newYamlLine(lines[0], num).marker() {
+			head += "---\n"
+		}
+	}
+	text := strings.Join(lines, "\n") + "\n"
+	var doc yaml.Node
+	err := yaml.Unmarshal([]byte(head+text), &doc)
+	offset := num - 1 - strings.Count(head, "\n")
+
+	if err != nil && prefix != "" && strings.Contains(err.Error(), "unknown anchor") {
+		var defs []*yaml.Node
+		for _, name := range st.order {
+			if strings.Contains(text, "*"+name) {
+				defs = append(defs, st.anchors[name])
+			}
+		}
+		if len(defs) > 0 {
+			indent := strings.Repeat(" ", newYamlLine(lines[0], num).indent)
+			b, merr := yaml.Marshal(&yaml.Node{Kind: yaml.SequenceNode, Content: st.dependencies(defs)})
+			if merr != nil {
+				return nil, merr
+			}
+			defText := strings.TrimSuffix(string(b), "\n")
+			defText = indent + prefix + "\n" + indent + "  " + strings.Replace(defText, "\n", "\n"+indent+"  ", -1) + "\n"
+			offset -= strings.Count(defText, "\n")
+			doc = yaml.Node{}
+			err = yaml.Unmarshal([]byte(head+defText+text), &doc)
+		}
+	}
+	if err != nil {
+		err = yamlError(err)
+		if e, ok := err.(*SyntaxError); ok && e.Line > 0 {
+			e.Line += offset
+		}
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	st.record(&doc, offset)
+	return &doc, nil
+}
+
+// record records the anchors defined within n, and moves n by offset
+// lines to its position in the stream.
+func (st *yamlStream) record(n *yaml.Node, offset int) {
+	n.Line += offset
+	if n.Anchor != "" && n.Kind != yaml.AliasNode {
+		if _, ok := st.anchors[n.Anchor]; !ok {
+			st.order = append(st.order, n.Anchor)
+		}
+		st.anchors[n.Anchor] = n
+	}
+	for _, c := range n.Content {
+		st.record(c, offset)
+	}
+}
+
+// dependencies returns the anchored nodes that nodes refer to, followed by
+// nodes, so that each anchor is defined before it is referred to.
+func (st *yamlStream) dependencies(nodes []*yaml.Node) []*yaml.Node {
+	var deps []*yaml.Node
+	done := make(map[*yaml.Node]bool)
+	var visit func(n *yaml.Node)
+	visit = func(n *yaml.Node) {
+		if n.Kind == yaml.AliasNode {
+			if def := st.anchors[n.Value]; def != nil && !done[def] {
+				done[def] = true
+				visit(def)
+				deps = append(deps, def)
+			}
+			return
+		}
+		for _, c := range n.Content {
+			visit(c)
+		}
+	}
+	for _, n := range nodes {
+		if !done[n] {
+			done[n] = true
+			visit(n)
+			deps = append(deps, n)
+		}
+	}
+	return deps
+}